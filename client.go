@@ -1,11 +1,17 @@
 package swiftapi
 
 import (
+	"reflect"
+
 	i "github.com/AbrahamBass/swiftapi/internal"
+	"github.com/AbrahamBass/swiftapi/internal/auth"
+	"github.com/AbrahamBass/swiftapi/internal/dependencies"
+	"github.com/AbrahamBass/swiftapi/internal/middlewares"
 	"github.com/AbrahamBass/swiftapi/internal/responses"
 	"github.com/AbrahamBass/swiftapi/internal/tasks"
 	"github.com/AbrahamBass/swiftapi/internal/testifyx"
 	"github.com/AbrahamBass/swiftapi/internal/types"
+	"github.com/AbrahamBass/swiftapi/internal/validation"
 	"github.com/AbrahamBass/swiftapi/internal/ws"
 )
 
@@ -13,6 +19,27 @@ type Application = types.IApplication
 
 var Bootstrap = i.NewApplication
 
+var RegisterParams = dependencies.RegisterParams
+
+// Converter registers fn on app to bind every parameter of type T.
+func Converter[T any](app Application, fn func(value string) (T, error)) {
+	app.AddConverter(reflect.TypeFor[T](), func(value string) (any, error) {
+		return fn(value)
+	})
+}
+
+// FormatProblems makes app render errors and invalid requests with fn
+// instead of problem+json.
+func FormatProblems(app Application, fn ProblemFormatter) {
+	app.(interface {
+		ProblemFormatter(responses.ProblemFormatter)
+	}).ProblemFormatter(fn)
+}
+
+var RegisterValidator = validation.RegisterValidator
+
+type ValidatorFunc = validation.Func
+
 var Describe = testifyx.Describe
 var Benchmark = testifyx.Benchmark
 var NewTestClient = testifyx.NewTestClient
@@ -60,6 +87,22 @@ func Streamer[T any](generator func(yield func(T) error) error) <-chan []byte {
 
 type IActionResult = responses.IActionResult
 
+type (
+	HTTPError        = responses.HTTPError
+	ProblemDetails   = responses.ProblemDetails
+	ProblemError     = responses.ProblemError
+	ProblemFormatter = responses.ProblemFormatter
+)
+
+var (
+	ErrBadRequest   = responses.ErrBadRequest
+	ErrUnauthorized = responses.ErrUnauthorized
+	ErrForbidden    = responses.ErrForbidden
+	ErrNotFound     = responses.ErrNotFound
+	ErrConflict     = responses.ErrConflict
+	ErrValidation   = responses.ErrValidation
+)
+
 type (
 	APIRoute  = types.IAPIRoute
 	APIRouter = types.IAPIRouter
@@ -97,10 +140,36 @@ type (
 	Scope[T any] struct {
 		Value T
 	}
+
+	// Claims decodes the claims of the authenticated principal into T.
+	Claims[T any] struct {
+		Value T
+	}
+)
+
+type Principal = auth.Principal
+
+type (
+	Policy     = types.IPolicy
+	PolicyFunc = middlewares.PolicyFunc
+)
+
+var (
+	HasScope = middlewares.HasScope
+	HasRole  = middlewares.HasRole
+	HasClaim = middlewares.HasClaim
+)
+
+var (
+	NewPrincipal  = auth.NewPrincipal
+	WithPrincipal = auth.WithPrincipal
+	PrincipalFrom = auth.FromRequest
 )
 
 type UploadFile = types.UploadFile
 
+type FileStream = types.FileStream
+
 type BackgroundTaskManager = tasks.BackgroundTaskManager
 
 type WebsocketManager = ws.WebsocketManager
@@ -118,4 +187,5 @@ const (
 	OctetStream            MediaType = types.OctetStream
 	ApplicationForm        MediaType = types.ApplicationForm
 	MultipartForm          MediaType = types.MultipartForm
+	ApplicationMsgPack     MediaType = types.ApplicationMsgPack
 )
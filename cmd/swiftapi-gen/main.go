@@ -0,0 +1,221 @@
+// Command swiftapi-gen records handler parameter names so swiftapi never
+// has to read Go source at runtime. Add to a package holding handlers:
+//
+//	//go:generate go run github.com/AbrahamBass/swiftapi/cmd/swiftapi-gen
+//
+// It writes swiftapi_params_gen.go, registering every top-level function
+// and method that takes a swiftapi parameter wrapper. Closures cannot be
+// named from another file and must be registered by hand.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const importPath = "github.com/AbrahamBass/swiftapi"
+
+var wrappers = map[string]bool{
+	"Query":      true,
+	"Pathway":    true,
+	"Signal":     true,
+	"Body":       true,
+	"Crumb":      true,
+	"Silk":       true,
+	"Dependency": true,
+	"Scope":      true,
+	"Claims":     true,
+}
+
+type registration struct {
+	expr  string
+	names []string
+}
+
+func main() {
+	output := flag.String("output", "swiftapi_params_gen.go", "file to write")
+	flag.Parse()
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	pkgName, regs, err := scan(dir, *output)
+	if err != nil {
+		log.Fatalf("swiftapi-gen: %v", err)
+	}
+
+	src, err := render(pkgName, regs)
+	if err != nil {
+		log.Fatalf("swiftapi-gen: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, *output), src, 0o644); err != nil {
+		log.Fatalf("swiftapi-gen: %v", err)
+	}
+}
+
+func scan(dir, output string) (string, []registration, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return "", nil, err
+	}
+
+	fset := token.NewFileSet()
+	pkgName := ""
+	var regs []registration
+
+	for _, path := range files {
+		base := filepath.Base(path)
+		if base == output || strings.HasSuffix(base, "_test.go") {
+			continue
+		}
+
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if pkgName == "" {
+			pkgName = file.Name.Name
+		}
+
+		alias := importAlias(file)
+		if alias == "" {
+			continue
+		}
+
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Type.TypeParams != nil || !usesWrapper(fn.Type, alias) {
+				continue
+			}
+
+			expr, ok := funcExpr(fn)
+			if !ok {
+				continue
+			}
+
+			regs = append(regs, registration{expr: expr, names: paramNames(fn.Type)})
+		}
+	}
+
+	if pkgName == "" {
+		return "", nil, fmt.Errorf("no Go files in %s", dir)
+	}
+
+	sort.Slice(regs, func(i, j int) bool { return regs[i].expr < regs[j].expr })
+	return pkgName, regs, nil
+}
+
+func importAlias(file *ast.File) string {
+	for _, imp := range file.Imports {
+		path, _ := strconv.Unquote(imp.Path.Value)
+		if path != importPath {
+			continue
+		}
+		if imp.Name != nil {
+			return imp.Name.Name
+		}
+		return "swiftapi"
+	}
+	return ""
+}
+
+func usesWrapper(fnType *ast.FuncType, alias string) bool {
+	for _, field := range fnType.Params.List {
+		typ := field.Type
+		switch t := typ.(type) {
+		case *ast.IndexExpr:
+			typ = t.X
+		case *ast.IndexListExpr:
+			typ = t.X
+		}
+
+		sel, ok := typ.(*ast.SelectorExpr)
+		if !ok {
+			continue
+		}
+		if pkg, ok := sel.X.(*ast.Ident); ok && pkg.Name == alias && wrappers[sel.Sel.Name] {
+			return true
+		}
+	}
+	return false
+}
+
+// funcExpr returns the expression naming fn from inside its package: the
+// function itself, or a method expression for methods.
+func funcExpr(fn *ast.FuncDecl) (string, bool) {
+	if fn.Recv == nil {
+		if fn.Name.Name == "init" || fn.Name.Name == "main" {
+			return "", false
+		}
+		return fn.Name.Name, true
+	}
+
+	recv := fn.Recv.List[0].Type
+	pointer := false
+	if star, ok := recv.(*ast.StarExpr); ok {
+		pointer = true
+		recv = star.X
+	}
+
+	ident, ok := recv.(*ast.Ident)
+	if !ok {
+		// Generic receivers cannot be named without instantiation.
+		return "", false
+	}
+
+	if pointer {
+		return "(*" + ident.Name + ")." + fn.Name.Name, true
+	}
+	return ident.Name + "." + fn.Name.Name, true
+}
+
+func paramNames(fnType *ast.FuncType) []string {
+	var names []string
+	for _, field := range fnType.Params.List {
+		if len(field.Names) == 0 {
+			names = append(names, "")
+		}
+		for _, name := range field.Names {
+			names = append(names, name.Name)
+		}
+	}
+	return names
+}
+
+func render(pkgName string, regs []registration) ([]byte, error) {
+	var b bytes.Buffer
+
+	b.WriteString("// Code generated by swiftapi-gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", pkgName)
+
+	if len(regs) > 0 {
+		fmt.Fprintf(&b, "import swiftapi %q\n\n", importPath)
+		b.WriteString("func init() {\n")
+		for _, reg := range regs {
+			quoted := make([]string, len(reg.names))
+			for i, name := range reg.names {
+				quoted[i] = strconv.Quote(name)
+			}
+			args := append([]string{reg.expr}, quoted...)
+			fmt.Fprintf(&b, "\tswiftapi.RegisterParams(%s)\n", strings.Join(args, ", "))
+		}
+		b.WriteString("}\n")
+	}
+
+	return format.Source(b.Bytes())
+}
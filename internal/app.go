@@ -2,11 +2,16 @@ package swiftapi
 
 import (
 	"fmt"
+	"io/fs"
 	"net/http"
+	"reflect"
+	"time"
 
 	"github.com/AbrahamBass/swiftapi/internal/builders"
+	"github.com/AbrahamBass/swiftapi/internal/dependencies"
 	"github.com/AbrahamBass/swiftapi/internal/logger"
 	"github.com/AbrahamBass/swiftapi/internal/middlewares"
+	"github.com/AbrahamBass/swiftapi/internal/responses"
 	"github.com/AbrahamBass/swiftapi/internal/tasks"
 	"github.com/AbrahamBass/swiftapi/internal/types"
 
@@ -17,20 +22,31 @@ import (
 type Application struct {
 	logger            *zap.Logger
 	di                types.IDigContainer
+	services          *dependencies.Services
+	registry          *dependencies.Registry
 	routers           []*APIRouter
-	staticFiles       map[string]string
+	staticFiles       map[string]http.Handler
 	globalMiddlewares []types.Middleware
 	jwtConfig         types.IJWTConfig
+	named             map[string]*APIRoute
+	bodyLimit         int64
+	multipartMemory   int64
+	timeout           time.Duration
+	errors            *responses.ErrorMapping
+	provided          bool
 }
 
 func NewApplication() *Application {
 	application := &Application{
 		routers:           []*APIRouter{},
-		staticFiles:       map[string]string{},
+		staticFiles:       map[string]http.Handler{},
 		globalMiddlewares: []types.Middleware{},
+		errors:            responses.NewErrorMapping(),
 	}
 	application.logger = logger.NewZapLogger()
 	application.di = dig.New()
+	application.services = dependencies.NewServices(application.di)
+	application.registry = dependencies.NewRegistry()
 	return application
 }
 
@@ -48,12 +64,21 @@ func (s *Application) AddMiddleware(middleware types.Middleware) {
 	s.globalMiddlewares = append(s.globalMiddlewares, middleware)
 }
 
+func (s *Application) AddStaticFile(prefix string, handler http.Handler) {
+	s.staticFiles[staticPrefix(prefix)] = handler
+}
+
+func (s *Application) StaticFileExists(prefix string) bool {
+	_, exists := s.staticFiles[staticPrefix(prefix)]
+	return exists
+}
+
 func (s *Application) SetJwtConfig(jwtConfig types.IJWTConfig) {
 	s.jwtConfig = jwtConfig
 }
 
 func (s *Application) Di() types.IContainerBuilder {
-	return builders.NewDi(s, s.di)
+	return builders.NewDi(s, s.services)
 }
 
 func (s *Application) CSRF() types.ICSRFBuilder {
@@ -84,24 +109,149 @@ func (s *Application) Sanitization() types.ISanitizationBuilder {
 	return builders.NewSanitizationBuilder(s)
 }
 
-func (s *Application) Mux() http.Handler {
+// ReflectionOnly stops the application from reading handler source files
+// to learn parameter names. Names must then come from RegisterParams,
+// usually through code generated by swiftapi-gen.
+func (s *Application) ReflectionOnly() {
+	s.registry.SetSourceLookup(false)
+}
+
+// AddConverter makes parameters of type typ bindable by parsing their raw
+// value with fn. It must be called before the handler is built.
+func (s *Application) AddConverter(typ reflect.Type, fn func(value string) (any, error)) {
+	s.registry.RegisterConverter(typ, fn)
+}
+
+// AddDecoder decodes request bodies sent as mediaType with fn, replacing
+// the built-in decoder for that media type if there is one.
+func (s *Application) AddDecoder(mediaType string, fn func(data []byte, v any) error) {
+	s.registry.RegisterDecoder(mediaType, fn)
+}
+
+// AddProvider makes fn build every Dependency[T] parameter, where T is
+// the first result of fn, instead of the DI container. fn takes the same
+// wrappers as a handler, other providers included, and runs at most once
+// per request.
+func (s *Application) AddProvider(fn interface{}) {
+	s.registry.RegisterProvider(fn)
+}
+
+// BodyLimit caps request bodies at limit bytes for every route that sets
+// no limit of its own, in place of DefaultBodyLimit. Larger bodies are
+// answered with 413; a negative limit removes the cap.
+func (s *Application) BodyLimit(limit int64) {
+	s.bodyLimit = limit
+}
+
+// MultipartMemory sets how many bytes of a multipart body are kept in
+// memory before file parts spill to temporary files on disk.
+func (s *Application) MultipartMemory(memory int64) {
+	s.multipartMemory = memory
+}
+
+// Timeout cancels the context of every request after d, unless its route
+// or router sets a timeout of its own.
+func (s *Application) Timeout(d time.Duration) {
+	s.timeout = d
+}
+
+// MapError answers handler errors matching target, as reported by
+// errors.Is, with a problem response of the given status.
+func (s *Application) MapError(target error, status int) {
+	s.errors.Map(target, status)
+}
+
+// AddErrorMapper adds fn to the mappers consulted, in order, for the
+// status of a handler error. Errors no mapper recognizes are answered
+// with their HTTPError status, or 500.
+func (s *Application) AddErrorMapper(fn func(err error) (status int, ok bool)) {
+	s.errors.Add(fn)
+}
+
+// ProblemFormatter replaces the problem+json rendering of errors and
+// invalid requests with fn.
+func (s *Application) ProblemFormatter(fn responses.ProblemFormatter) {
+	s.errors.SetFormatter(fn)
+}
+
+// Messages adds translations for lang, keyed by the English message
+// formats, to those the application uses for clients whose
+// Accept-Language prefers it.
+func (s *Application) Messages(lang string, catalog map[string]string) {
+	s.errors.Messages().Register(lang, catalog)
+}
+
+// TimeLayouts sets the layouts tried, in order, when binding a time.Time.
+func (s *Application) TimeLayouts(layouts ...string) {
+	s.registry.SetTimeLayouts(layouts...)
+}
+
+func (s *Application) Static(prefix string, fsys fs.FS) types.IStaticBuilder {
+	return builders.NewStaticBuilder(s, prefix, fsys)
+}
+
+// Handler compiles the registered routes and returns the resulting
+// mux, or every handler and route problem found, as one error.
+func (s *Application) Handler() (http.Handler, error) {
 	defer s.logger.Sync()
 
 	mux := newMux()
-	mux.SetDig(s.di)
+	mux.SetServices(s.services)
+	mux.SetRegistry(s.registry)
 	mux.SetLogger(s.logger)
 	mux.SetRouters(s.routers)
 	mux.SetStaticFile(s.staticFiles)
 	mux.SetGlobalMiddlewares(s.globalMiddlewares)
 	mux.SetJwtConfig(s.jwtConfig)
+	mux.SetBodyLimit(s.bodyLimit)
+	mux.SetMultipartMemory(s.multipartMemory)
+	mux.SetTimeout(s.timeout)
+	mux.SetErrorMapping(s.errors)
+
+	// The built-in services are provided first, so that handlers taking
+	// them pass analysis.
+	if !s.provided {
+		_ = s.Di().
+			Provide(s.GetLogger).
+			Provide(tasks.NewBackgroundTaskManager)
+		s.provided = true
+	}
 
-	_ = s.Di().
-		Provide(s.GetLogger).
-		Provide(tasks.NewBackgroundTaskManager)
+	if err := mux.Compile(); err != nil {
+		return nil, err
+	}
+	s.named, _ = namedRoutes(s.routers)
 
+	return mux, nil
+}
+
+func (s *Application) Mux() http.Handler {
+	mux, err := s.Handler()
+	if err != nil {
+		s.logger.Fatal("🚨 Failed to compile routes",
+			zap.Error(err),
+		)
+	}
 	return mux
 }
 
+func (s *Application) URLFor(name string, params map[string]string) (string, error) {
+	named := s.named
+	if named == nil {
+		var err error
+		if named, err = namedRoutes(s.routers); err != nil {
+			return "", err
+		}
+	}
+
+	route, ok := named[name]
+	if !ok {
+		return "", fmt.Errorf("no route named %q", name)
+	}
+
+	return route.path.Build(params)
+}
+
 func (s *Application) Build(port int) types.IApplication {
 	addr := fmt.Sprintf(":%d", port)
 
@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Principal is the authenticated caller of a request, as established by
+// whichever authentication scheme accepted it.
+type Principal struct {
+	Scheme  string
+	Subject string
+	Scopes  []string
+	Roles   []string
+	Claims  map[string]any
+}
+
+// NewPrincipal builds the principal described by claims. The subject is
+// read from "sub", scopes from "scope" or "scp" and roles from "roles" or
+// "role", each either a space separated string or a list.
+func NewPrincipal(scheme string, claims map[string]any) *Principal {
+	p := &Principal{
+		Scheme: scheme,
+		Claims: claims,
+	}
+	p.Subject, _ = claims["sub"].(string)
+
+	for _, key := range []string{"scope", "scp"} {
+		p.Scopes = append(p.Scopes, List(claims[key])...)
+	}
+	for _, key := range []string{"roles", "role"} {
+		p.Roles = append(p.Roles, List(claims[key])...)
+	}
+	return p
+}
+
+// List reads a claim holding either a space separated string or a list of
+// strings.
+func List(claim any) []string {
+	switch v := claim.(type) {
+	case string:
+		return strings.Fields(v)
+	case []string:
+		return v
+	case []any:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+func (p *Principal) HasRole(role string) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+// Claim returns the claim called name.
+func (p *Principal) Claim(name string) (any, bool) {
+	v, ok := p.Claims[name]
+	return v, ok
+}
+
+// Lookup returns the claim at path, whose dot separated segments descend
+// into nested objects, as in "realm_access.roles".
+func (p *Principal) Lookup(path string) (any, bool) {
+	var current any = p.Claims
+	for _, key := range strings.Split(path, ".") {
+		object, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if current, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// Decode fills v, usually a pointer to a struct, from the claims by their
+// JSON names.
+func (p *Principal) Decode(v any) error {
+	data, err := json.Marshal(p.Claims)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of r authenticated as p. Authentication
+// schemes call it, or SetPrincipal on the request scope, once they have
+// accepted the request.
+func WithPrincipal(r *http.Request, p *Principal) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), principalKey{}, p))
+}
+
+// FromRequest returns the principal r was authenticated as.
+func FromRequest(r *http.Request) (*Principal, bool) {
+	p, ok := r.Context().Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
@@ -1,8 +1,13 @@
 package builders
 
 import (
+	"context"
+	"time"
+
 	"github.com/AbrahamBass/swiftapi/internal/middlewares"
 	"github.com/AbrahamBass/swiftapi/internal/types"
+
+	"go.uber.org/zap"
 )
 
 type JWTBearer struct {
@@ -37,6 +42,43 @@ func (jb *JWTBearer) Issue(issuer []string) types.IJWTBuilder {
 	return jb
 }
 
+// PublicKey verifies tokens naming kid, or tokens without a kid when kid
+// is empty, with the RSA, ECDSA or Ed25519 public key in pem. Its
+// algorithm must also be allowed with Algorithms.
+func (jb *JWTBearer) PublicKey(kid string, pem string) types.IJWTBuilder {
+	key, err := middlewares.ParsePublicKeyPEM([]byte(pem))
+	if err != nil {
+		jb.app.GetLogger().Fatal("🚨 JWT public key",
+			zap.String("kid", kid),
+			zap.String("msg", err.Error()),
+		)
+	}
+	jb.config.AddPublicKey(kid, key)
+	return jb
+}
+
+// JWKSFile verifies tokens with the key set in the file at path, read
+// again every refresh interval.
+func (jb *JWTBearer) JWKSFile(path string, refresh time.Duration) types.IJWTBuilder {
+	keySet := middlewares.NewJWKSFile(path, refresh)
+	if err := keySet.Refresh(context.Background()); err != nil {
+		jb.app.GetLogger().Fatal("🚨 JWKS",
+			zap.String("path", path),
+			zap.String("msg", err.Error()),
+		)
+	}
+	jb.config.SetKeySet(keySet)
+	return jb
+}
+
+// JWKSURL verifies tokens with the key set served at url. The set is
+// fetched on the first request and again every refresh interval, or when
+// a token names a key it does not hold.
+func (jb *JWTBearer) JWKSURL(url string, refresh time.Duration) types.IJWTBuilder {
+	jb.config.SetKeySet(middlewares.NewJWKSURL(url, refresh, nil))
+	return jb
+}
+
 func (jb *JWTBearer) Apply() types.IApplication {
 	jb.app.SetJwtConfig(jb.config)
 	return jb.app
@@ -8,10 +8,10 @@ import (
 
 type Container struct {
 	app types.IApplication
-	dig types.IDigContainer
+	dig types.IServiceContainer
 }
 
-func NewDi(app types.IApplication, dig types.IDigContainer) *Container {
+func NewDi(app types.IApplication, dig types.IServiceContainer) *Container {
 	return &Container{
 		app: app,
 		dig: dig,
@@ -28,6 +28,29 @@ func (c *Container) Provide(constructor interface{}) types.IContainerBuilder {
 	return c
 }
 
+// Scoped registers constructor to build one instance per request. It may
+// take the *http.Request or RequestScope and return a cleanup func that
+// runs after the response is written.
+func (c *Container) Scoped(constructor interface{}) types.IContainerBuilder {
+	if err := c.dig.Scoped(constructor); err != nil {
+		c.app.GetLogger().Fatal("🚨 dependency registration",
+			zap.String("msg", err.Error()),
+		)
+	}
+	return c
+}
+
+// Transient registers constructor to build a new instance every time one
+// is injected.
+func (c *Container) Transient(constructor interface{}) types.IContainerBuilder {
+	if err := c.dig.Transient(constructor); err != nil {
+		c.app.GetLogger().Fatal("🚨 dependency registration",
+			zap.String("msg", err.Error()),
+		)
+	}
+	return c
+}
+
 func (d *Container) Apply() types.IApplication {
 	return d.app
 }
@@ -0,0 +1,51 @@
+package builders
+
+import (
+	"io/fs"
+
+	"github.com/AbrahamBass/swiftapi/internal/static"
+	"github.com/AbrahamBass/swiftapi/internal/types"
+
+	"go.uber.org/zap"
+)
+
+type StaticBuilder struct {
+	app    types.IApplication
+	prefix string
+	fsys   fs.FS
+	config types.IStaticConfig
+}
+
+func NewStaticBuilder(app types.IApplication, prefix string, fsys fs.FS) *StaticBuilder {
+	return &StaticBuilder{
+		app:    app,
+		prefix: prefix,
+		fsys:   fsys,
+		config: static.NewStaticConfig(),
+	}
+}
+
+func (sb *StaticBuilder) Index(file string) types.IStaticBuilder {
+	sb.config.SetIndex(file)
+	return sb
+}
+
+func (sb *StaticBuilder) Fallback(file string) types.IStaticBuilder {
+	sb.config.SetFallback(file)
+	return sb
+}
+
+func (sb *StaticBuilder) Precompressed(enabled bool) types.IStaticBuilder {
+	sb.config.SetPrecompressed(enabled)
+	return sb
+}
+
+func (sb *StaticBuilder) Apply() types.IApplication {
+	if sb.app.StaticFileExists(sb.prefix) {
+		sb.app.GetLogger().Fatal("🚨 static files already mounted",
+			zap.String("prefix", sb.prefix),
+		)
+	}
+	sb.app.AddStaticFile(sb.prefix, static.NewFileServer(sb.fsys, sb.config))
+	return sb.app
+}
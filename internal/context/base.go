@@ -6,19 +6,36 @@ import (
 	"net/http"
 	"net/url"
 
+	"github.com/AbrahamBass/swiftapi/internal/auth"
 	"github.com/AbrahamBass/swiftapi/internal/responses"
 	"github.com/AbrahamBass/swiftapi/internal/types"
 )
 
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of r carrying id as its request ID.
+func WithRequestID(r *http.Request, id string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id))
+}
+
+// RequestID returns the ID the logging middleware gave r, or "" when the
+// request did not go through it.
+func RequestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey{}).(string)
+	return id
+}
+
 type Middleware struct {
-	req *http.Request
-	res *responses.ResponseWriter
+	req    *http.Request
+	res    *responses.ResponseWriter
+	errors *responses.ErrorMapping
 }
 
-func NewContext(w *responses.ResponseWriter, r *http.Request) *Middleware {
+func NewContext(w *responses.ResponseWriter, r *http.Request, errors *responses.ErrorMapping) *Middleware {
 	return &Middleware{
-		req: r,
-		res: w,
+		req:    r,
+		res:    w,
+		errors: errors,
 	}
 }
 
@@ -89,10 +106,30 @@ func (c *Middleware) Respond(status int, v interface{}) {
 	c.res.Send(v)
 }
 
+// Throw stops the request with status. Messages and errors are answered
+// as a problem document, with the message as its detail.
 func (c *Middleware) Throw(status int, err interface{}) {
-	c.res.MediaType = types.TextPlain
-	c.res.StatusCode = status
-	c.res.Send(err)
+	var detail string
+	switch v := err.(type) {
+	case string:
+		detail = v
+	case error:
+		detail = v.Error()
+	default:
+		c.res.MediaType = types.TextPlain
+		c.res.StatusCode = status
+		c.res.Send(err)
+		return
+	}
+
+	c.res.Send(c.errors.Respond(c.req, responses.ProblemDetails{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  c.req.URL.Path,
+		RequestID: RequestID(c.req),
+	}))
 }
 
 func (c *Middleware) SetStatus(status int) {
@@ -116,6 +153,15 @@ func (c *Middleware) SetBaggage(key string, value any) {
 	c.req = c.req.WithContext(ctx)
 }
 
+// SetPrincipal authenticates the rest of the request as p.
+func (c *Middleware) SetPrincipal(p *auth.Principal) {
+	c.req = auth.WithPrincipal(c.req, p)
+}
+
+func (c *Middleware) Principal() (*auth.Principal, bool) {
+	return auth.FromRequest(c.req)
+}
+
 func (c *Middleware) SecureChannel() *tls.ConnectionState {
 	return c.req.TLS
 }
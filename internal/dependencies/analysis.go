@@ -0,0 +1,200 @@
+package dependencies
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/AbrahamBass/swiftapi/internal/types"
+	"github.com/AbrahamBass/swiftapi/internal/validation"
+)
+
+type ParamError struct {
+	Name   string
+	Type   reflect.Type
+	Reason string
+}
+
+func newParamError(name string, typ reflect.Type, reason string) *ParamError {
+	return &ParamError{
+		Name:   name,
+		Type:   typ,
+		Reason: reason,
+	}
+}
+
+func (e *ParamError) Error() string {
+	if e.Type == nil {
+		return fmt.Sprintf("parameter %q: %s", e.Name, e.Reason)
+	}
+	return fmt.Sprintf("parameter %q (%s): %s", e.Name, e.Type, e.Reason)
+}
+
+// HandlerError reports why a handler cannot be bound. Err is set when the
+// handler could not be analyzed at all; otherwise Params lists every
+// offending parameter.
+type HandlerError struct {
+	Func   string
+	Err    error
+	Params []*ParamError
+}
+
+func (e *HandlerError) Error() string {
+	var b strings.Builder
+	b.WriteString("handler " + e.Func)
+
+	if e.Err != nil {
+		b.WriteString(": " + e.Err.Error())
+	}
+
+	for _, p := range e.Params {
+		b.WriteString("\n  - " + p.Error())
+	}
+	return b.String()
+}
+
+func (e *HandlerError) Unwrap() error {
+	return e.Err
+}
+
+func funcName(fn interface{}) string {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func {
+		return fmt.Sprintf("%T", fn)
+	}
+	return runtime.FuncForPC(v.Pointer()).Name()
+}
+
+// Analyze checks that handler can be bound for a route whose pattern
+// declares pathParams and whose parameters carry tags, and that the scoped
+// and transient services it needs can be built. It is meant to run once at
+// startup so that broken signatures surface before the first request does.
+func (reg *Registry) Analyze(handler interface{}, pathParams []string, tags ParamTags, services *Services) error {
+	depend, err := reg.analyzeDependenciesWithCache(handler)
+	if err != nil {
+		return err
+	}
+
+	if err := checkResults(reflect.TypeOf(handler)); err != nil {
+		return &HandlerError{Func: funcName(handler), Err: err}
+	}
+
+	var problems []*ParamError
+	for _, d := range depend.withProviders() {
+		for _, field := range d.PathParams {
+			if !slices.Contains(pathParams, field.Name) {
+				problems = append(problems, newParamError(
+					field.Name,
+					field.ReflectType,
+					"no matching {"+field.Name+"} placeholder in the route path",
+				))
+			}
+		}
+
+		for _, sm := range d.StructParams {
+			for _, field := range sm.pathKeys() {
+				if !slices.Contains(pathParams, field.Key) {
+					problems = append(problems, newParamError(
+						field.Name,
+						field.Type,
+						"no matching {"+field.Key+"} placeholder in the route path",
+					))
+				}
+			}
+		}
+
+		for _, field := range d.ServiceParams {
+			if err := services.check(field.Type, nil); err != nil {
+				problems = append(problems, newParamError(field.Name, field.ReflectType, err.Error()))
+			}
+		}
+	}
+
+	problems = append(problems, reg.checkTags(depend, tags)...)
+
+	if len(problems) > 0 {
+		return &HandlerError{Func: funcName(handler), Params: problems}
+	}
+	return nil
+}
+
+// checkResults accepts handlers returning nothing, a single value, or a
+// value and an error.
+func checkResults(fnType reflect.Type) error {
+	switch {
+	case fnType.NumOut() <= 1:
+		return nil
+	case fnType.NumOut() == 2 && fnType.Out(1) == errorType:
+		return nil
+	}
+	return fmt.Errorf("handlers must return nothing, T, error or (T, error), not %d values", fnType.NumOut())
+}
+
+func (reg *Registry) checkConvertible(field *model, source types.TagType) *ParamError {
+	if reg.convertible(indirect(field.Type)) {
+		return nil
+	}
+	return newParamError(
+		field.Name,
+		field.ReflectType,
+		fmt.Sprintf("%s values are strings and cannot be converted to %s", source, field.Type),
+	)
+}
+
+// needsName reports whether a wrapper is bound by its parameter name.
+func needsName(tag types.TagType) bool {
+	switch tag {
+	case types.TagBody, types.TagService, types.TagClaims:
+		return false
+	default:
+		return true
+	}
+}
+
+// checkTags reports route tags naming no parameter, defaults that are
+// misplaced or cannot be converted, and validate rules that do not apply.
+func (reg *Registry) checkTags(depend *dependant, tags ParamTags) []*ParamError {
+	defaultable := slices.Concat(depend.QueryParams, depend.HeaderParams, depend.CookieParams, depend.FormParams)
+	all := slices.Concat(defaultable, depend.PathParams, depend.BodyParams, depend.ServiceParams, depend.ContextParams)
+	for _, pm := range depend.Providers {
+		all = append(all, pm.model)
+	}
+
+	names := make([]string, 0, len(tags))
+	for name := range tags {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var problems []*ParamError
+	for _, name := range names {
+		opts := parseOptions(tags[name])
+
+		i := slices.IndexFunc(all, func(m *model) bool { return m.Name == name })
+		if i < 0 {
+			problems = append(problems, newParamError(name, nil, "tagged on the route but the handler has no such parameter"))
+			continue
+		}
+		target := all[i]
+
+		if opts.Validate != "" {
+			if err := validation.Check(opts.Validate, target.Type); err != nil {
+				problems = append(problems, newParamError(name, target.ReflectType, err.Error()))
+			}
+		}
+
+		if i >= len(defaultable) {
+			if opts.HasDefault {
+				problems = append(problems, newParamError(name, target.ReflectType, "defaults apply only to query, header, cookie and form parameters"))
+			}
+			continue
+		}
+
+		if problem := reg.checkDefault(name, target.Type, opts); problem != nil {
+			problems = append(problems, problem)
+		}
+	}
+	return problems
+}
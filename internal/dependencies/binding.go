@@ -0,0 +1,357 @@
+package dependencies
+
+import (
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/AbrahamBass/swiftapi/internal/types"
+	"github.com/AbrahamBass/swiftapi/internal/validation"
+)
+
+// bindingTags are the struct tags a request struct may use to pull a field
+// from somewhere other than the JSON body.
+var bindingTags = []string{
+	types.ParamLocationPath,
+	types.ParamLocationQuery,
+	types.ParamLocationHeader,
+	types.ParamLocationCookie,
+}
+
+type structField struct {
+	Index    []int
+	Name     string
+	Key      string
+	Location string
+	Type     reflect.Type
+	Options  fieldOptions
+}
+
+// structModel describes a handler parameter that is a plain struct, or a
+// pointer to one, whose fields are bound by tag in a single pass:
+//
+//	type GetItem struct {
+//		ID     int     `path:"id"`
+//		Page   *int    `query:"page"`
+//		Tenant string  `header:"X-Tenant"`
+//		Name   string  `json:"name"`
+//	}
+//
+// Fields tagged only with json are decoded from the body. Pointer fields
+// are optional and a `default:"..."` tag fills in a missing value; every
+// other field must be present in the request. Slice fields collect every
+// value of a repeated key, split on the separator of a `split:","` tag.
+// Any field may carry `validate:"..."` rules, checked once it is bound.
+type structModel struct {
+	*model
+	Pointer      bool
+	Fields       []*structField
+	BodyFields   []*structField
+	Body         bool
+	BodyRequired bool
+}
+
+// requestStruct reports whether t is a struct, or pointer to a struct,
+// carrying at least one binding or json tag on its fields.
+func requestStruct(t reflect.Type) (reflect.Type, bool) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, false
+	}
+
+	for _, field := range reflect.VisibleFields(t) {
+		if _, ok := field.Tag.Lookup("json"); ok {
+			return t, true
+		}
+		for _, tag := range bindingTags {
+			if _, ok := field.Tag.Lookup(tag); ok {
+				return t, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func tagName(value string, field reflect.StructField) string {
+	name, _, _ := strings.Cut(value, ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+func (reg *Registry) newStructModel(p param, structType reflect.Type) (*structModel, []*ParamError) {
+	sm := &structModel{
+		model:   newModel(p.I, p.Name, structType, p.ReflectType),
+		Pointer: p.ReflectType.Kind() == reflect.Ptr,
+	}
+
+	// Request structs need no parameter name, so fall back to the type
+	// name in error messages when the name is unknown.
+	prefix := p.Name
+	if p.NameErr != nil {
+		prefix = structType.Name()
+	}
+
+	var problems []*ParamError
+	for _, field := range reflect.VisibleFields(structType) {
+		if field.Anonymous && indirect(field.Type).Kind() == reflect.Struct {
+			if field.Type.Kind() == reflect.Ptr && !field.IsExported() {
+				problems = append(problems, newParamError(prefix+"."+field.Name, field.Type, "embedded pointer is unexported and cannot be allocated"))
+			}
+			continue
+		}
+
+		location, key := "", ""
+		for _, tag := range bindingTags {
+			if value, ok := field.Tag.Lookup(tag); ok && value != "-" {
+				location, key = tag, tagName(value, field)
+				break
+			}
+		}
+
+		name := prefix + "." + field.Name
+
+		if location == "" {
+			value, ok := field.Tag.Lookup("json")
+			if !ok || value == "-" {
+				continue
+			}
+			if !field.IsExported() {
+				problems = append(problems, newParamError(name, field.Type, "field is unexported and cannot be decoded"))
+				continue
+			}
+			sm.Body = true
+			if field.Type.Kind() != reflect.Ptr && !strings.Contains(value, "omitempty") {
+				sm.BodyRequired = true
+			}
+
+			bf := &structField{
+				Index:    field.Index,
+				Name:     name,
+				Key:      validation.JSONName(field),
+				Location: "body",
+				Type:     field.Type,
+				Options:  parseOptions(field.Tag),
+			}
+			if err := checkValidation(bf); err != nil {
+				problems = append(problems, newParamError(name, field.Type, err.Error()))
+				continue
+			}
+			sm.BodyFields = append(sm.BodyFields, bf)
+			continue
+		}
+
+		if !field.IsExported() {
+			problems = append(problems, newParamError(name, field.Type, "field is unexported and cannot be bound"))
+			continue
+		}
+
+		sf := &structField{
+			Index:    field.Index,
+			Name:     name,
+			Key:      key,
+			Location: location,
+			Type:     field.Type,
+			Options:  parseOptions(field.Tag),
+		}
+
+		if !reg.convertible(indirect(sf.Type)) {
+			problems = append(problems, newParamError(
+				name,
+				field.Type,
+				fmt.Sprintf("%s values are strings and cannot be converted to %s", location, indirect(sf.Type)),
+			))
+			continue
+		}
+
+		if location == types.ParamLocationPath && sf.Options.HasDefault {
+			problems = append(problems, newParamError(name, field.Type, "defaults apply only to query, header, cookie and form parameters"))
+			continue
+		}
+
+		if problem := reg.checkDefault(name, sf.Type, sf.Options); problem != nil {
+			problems = append(problems, problem)
+			continue
+		}
+
+		if err := checkValidation(sf); err != nil {
+			problems = append(problems, newParamError(name, field.Type, err.Error()))
+			continue
+		}
+
+		sm.Fields = append(sm.Fields, sf)
+	}
+
+	return sm, problems
+}
+
+func checkValidation(field *structField) error {
+	if field.Options.Validate != "" {
+		if err := validation.Check(field.Options.Validate, field.Type); err != nil {
+			return err
+		}
+	}
+	return validation.CheckType(field.Type)
+}
+
+func (sm *structModel) pathKeys() []*structField {
+	var fields []*structField
+	for _, field := range sm.Fields {
+		if field.Location == types.ParamLocationPath {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// requestValues gathers every input location once per request so that
+// several request structs do not reparse the same data.
+type requestValues struct {
+	path    map[string][]string
+	query   url.Values
+	header  http.Header
+	cookies map[string][]string
+}
+
+func newRequestValues(req *http.Request) *requestValues {
+	return &requestValues{
+		path:    parseParams(req),
+		query:   req.URL.Query(),
+		header:  req.Header,
+		cookies: parseCookies(req),
+	}
+}
+
+func (rv *requestValues) lookup(location, key string) []string {
+	switch location {
+	case types.ParamLocationPath:
+		return rv.path[key]
+	case types.ParamLocationQuery:
+		return rv.query[key]
+	case types.ParamLocationHeader:
+		return rv.header.Values(key)
+	case types.ParamLocationCookie:
+		return rv.cookies[key]
+	}
+	return nil
+}
+
+func (reg *Registry) bindStruct(sm *structModel, values *requestValues, body interface{}) (reflect.Value, []*issue) {
+	var issues []*issue
+	target := reflect.New(sm.Type)
+
+	if sm.Body {
+		if bodyIssues := reg.decodeStructBody(sm, target, body); len(bodyIssues) > 0 {
+			issues = append(issues, bodyIssues...)
+		} else {
+			for _, field := range sm.BodyFields {
+				value := fieldOrZero(target.Elem(), field.Index, field.Type)
+				issues = append(issues, validateValue(value, field.Options.Validate, []string{"body", field.Key})...)
+			}
+		}
+	}
+
+	for _, field := range sm.Fields {
+		loc := []string{field.Location, field.Key}
+
+		raws := reg.headerValues(field.Location, field.Type, values.lookup(field.Location, field.Key))
+		converted, fieldIssues := reg.inputValue(raws, field.Type, field.Options, loc)
+		if len(fieldIssues) > 0 {
+			issues = append(issues, fieldIssues...)
+			continue
+		}
+
+		dest := allocField(target.Elem(), field.Index)
+		if converted != nil {
+			value := reflect.ValueOf(converted).Convert(indirect(field.Type))
+			if field.Type.Kind() == reflect.Ptr {
+				ptr := reflect.New(value.Type())
+				ptr.Elem().Set(value)
+				value = ptr
+			}
+			dest.Set(value)
+		}
+
+		issues = append(issues, validateValue(dest, field.Options.Validate, loc)...)
+	}
+
+	if sm.Pointer {
+		return target, issues
+	}
+	return target.Elem(), issues
+}
+
+func (reg *Registry) decodeStructBody(sm *structModel, target reflect.Value, body interface{}) []*issue {
+	loc := []string{"body"}
+
+	switch b := body.(type) {
+	case nil:
+		if !sm.BodyRequired {
+			return nil
+		}
+		return []*issue{newIssue(loc, "request body is required", types.Missing)}
+	case *rawBody:
+		if bodyIssue := reg.decodeBody(b, target.Interface(), loc); bodyIssue != nil {
+			return []*issue{bodyIssue}
+		}
+		return nil
+	case url.Values:
+		return reg.decodeValues(b, target.Interface(), loc)
+	case *multipart.Form:
+		return reg.decodeValues(b.Value, target.Interface(), loc)
+	default:
+		return []*issue{newIssue(
+			loc,
+			"invalid body type: %T",
+			types.InvalidType,
+			body,
+		)}
+	}
+}
+
+func (reg *Registry) processStructs(args arguments, structs []*structModel, req *http.Request, body interface{}) []*issue {
+	var issues []*issue
+	values := newRequestValues(req)
+
+	for _, sm := range structs {
+		value, structIssues := reg.bindStruct(sm, values, body)
+		issues = append(issues, structIssues...)
+		args[sm.I] = value
+	}
+	return issues
+}
+
+// allocField returns the field of v at index, allocating the nil embedded
+// struct pointers on the way. It returns the zero Value when one of them
+// is unexported and cannot be set.
+func allocField(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// fieldOrZero returns the field of v at index, or the zero value of typ
+// when it is promoted through a nil embedded pointer.
+func fieldOrZero(v reflect.Value, index []int, typ reflect.Type) reflect.Value {
+	field, err := v.FieldByIndexErr(index)
+	if err != nil {
+		return reflect.Zero(typ)
+	}
+	return field
+}
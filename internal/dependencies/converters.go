@@ -0,0 +1,88 @@
+package dependencies
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Converter parses one raw request value into the type it was registered
+// for.
+type Converter func(value string) (any, error)
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	uuidType            = reflect.TypeOf(uuid.UUID{})
+)
+
+// RegisterConverter makes typ bindable from path, query, header, cookie
+// and form values. It takes precedence over every built-in conversion.
+func (reg *Registry) RegisterConverter(typ reflect.Type, fn Converter) {
+	reg.converters.Store(typ, fn)
+}
+
+// SetTimeLayouts replaces the layouts tried, in order, when parsing a
+// time.Time. The default accepts RFC 3339, "2006-01-02 15:04:05" and
+// "2006-01-02".
+func (reg *Registry) SetTimeLayouts(layouts ...string) {
+	reg.layoutsMu.Lock()
+	defer reg.layoutsMu.Unlock()
+	reg.timeLayouts = layouts
+}
+
+func (reg *Registry) parseTime(value string) (time.Time, error) {
+	reg.layoutsMu.RLock()
+	defer reg.layoutsMu.RUnlock()
+
+	for _, layout := range reg.timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q does not match any accepted time layout", value)
+}
+
+// customConverter returns the conversion for types handled before the
+// primitive kinds: registered converters, time, durations, UUIDs and any
+// type implementing encoding.TextUnmarshaler.
+func (reg *Registry) customConverter(t reflect.Type) (Converter, bool) {
+	if fn, ok := reg.converters.Load(t); ok {
+		conv := fn.(Converter)
+		return func(value string) (any, error) {
+			result, err := conv(value)
+			if err != nil {
+				return nil, err
+			}
+			if result == nil || !reflect.TypeOf(result).ConvertibleTo(t) {
+				return nil, fmt.Errorf("converter for %s returned %T", t, result)
+			}
+			return result, nil
+		}, true
+	}
+
+	switch t {
+	case timeType:
+		return func(value string) (any, error) { return reg.parseTime(value) }, true
+	case durationType:
+		return func(value string) (any, error) { return time.ParseDuration(value) }, true
+	case uuidType:
+		return func(value string) (any, error) { return uuid.Parse(value) }, true
+	}
+
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return func(value string) (any, error) {
+			ptr := reflect.New(t)
+			if err := ptr.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value)); err != nil {
+				return nil, err
+			}
+			return ptr.Elem().Interface(), nil
+		}, true
+	}
+
+	return nil, false
+}
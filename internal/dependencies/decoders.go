@@ -0,0 +1,178 @@
+package dependencies
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/AbrahamBass/swiftapi/internal/types"
+	"github.com/AbrahamBass/swiftapi/internal/validation"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Decoder unmarshals a request body into v, a pointer to the target.
+type Decoder func(data []byte, v any) error
+
+// RegisterDecoder makes bodies sent as mediaType decodable into Body
+// parameters and request structs, replacing any decoder already set.
+func (reg *Registry) RegisterDecoder(mediaType string, fn Decoder) {
+	reg.decoders.Store(strings.ToLower(mediaType), fn)
+}
+
+// decoderFor finds the decoder for a media type. Structured syntax
+// suffixes such as application/problem+json fall back to the decoder of
+// their base format, and a missing Content-Type is read as JSON.
+func (reg *Registry) decoderFor(mediaType string) (Decoder, bool) {
+	if mediaType == "" {
+		mediaType = string(types.ApplicationJSON)
+	}
+
+	if fn, ok := reg.decoders.Load(mediaType); ok {
+		return fn.(Decoder), true
+	}
+
+	switch {
+	case strings.HasSuffix(mediaType, "+json"):
+		return reg.decoderFor(string(types.ApplicationJSON))
+	case strings.HasSuffix(mediaType, "+xml"):
+		return reg.decoderFor(string(types.ApplicationXML))
+	}
+	return nil, false
+}
+
+// rawBody is a request body that was not parsed as a form.
+type rawBody struct {
+	data      []byte
+	mediaType string
+}
+
+func decodeMsgPack(data []byte, v any) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
+
+func decodePlain(data []byte, v any) error {
+	switch target := v.(type) {
+	case *string:
+		*target = string(data)
+	case *[]byte:
+		*target = data
+	case encoding.TextUnmarshaler:
+		return target.UnmarshalText(data)
+	default:
+		return fmt.Errorf("text/plain bodies cannot be decoded into %T", v)
+	}
+	return nil
+}
+
+func (reg *Registry) decodeBody(body *rawBody, v any, loc []string) *issue {
+	decode, ok := reg.decoderFor(body.mediaType)
+	if !ok {
+		return newIssue(
+			loc,
+			"Unsupported media type %q",
+			types.UnsupportedMediaType,
+			body.mediaType,
+		)
+	}
+
+	if err := decode(body.data, v); err != nil {
+		if body.mediaType == "" || body.mediaType == string(types.ApplicationJSON) || strings.HasSuffix(body.mediaType, "+json") {
+			return parseJSONError(err, loc)
+		}
+		return newIssue(
+			loc,
+			"Invalid %s body: %v",
+			types.Syntax,
+			body.mediaType, err,
+		)
+	}
+	return nil
+}
+
+// decodeValues fills the struct or map v points to from form values.
+// Struct fields are matched by their form tag, falling back to the JSON
+// name, and accept the same default and split tags as parameters.
+func (reg *Registry) decodeValues(values url.Values, v any, loc []string) []*issue {
+	target := reflect.ValueOf(v).Elem()
+
+	switch target.Kind() {
+	case reflect.Map:
+		if target.Type().Key().Kind() != reflect.String {
+			break
+		}
+		if target.IsNil() {
+			target.Set(reflect.MakeMap(target.Type()))
+		}
+
+		var issues []*issue
+		for key, raws := range values {
+			elemType := target.Type().Elem()
+			if elemType.Kind() == reflect.Interface {
+				elemType = reflect.TypeOf("")
+			}
+
+			converted, fieldIssues := reg.inputValue(raws, elemType, fieldOptions{}, append(slices.Clip(loc), key))
+			if len(fieldIssues) > 0 {
+				issues = append(issues, fieldIssues...)
+				continue
+			}
+			target.SetMapIndex(reflect.ValueOf(key), reflect.ValueOf(converted).Convert(target.Type().Elem()))
+		}
+		return issues
+	case reflect.Struct:
+		var issues []*issue
+		for _, field := range reflect.VisibleFields(target.Type()) {
+			if !field.IsExported() || field.Anonymous {
+				continue
+			}
+
+			key := validation.JSONName(field)
+			if tag, ok := field.Tag.Lookup("form"); ok {
+				key = tagName(tag, field)
+			}
+			if key == "" || key == "-" {
+				continue
+			}
+
+			opts := parseOptions(field.Tag)
+			raws, present := values[key]
+			if !present && !opts.HasDefault {
+				continue
+			}
+
+			converted, fieldIssues := reg.inputValue(raws, field.Type, opts, append(slices.Clip(loc), key))
+			if len(fieldIssues) > 0 {
+				issues = append(issues, fieldIssues...)
+				continue
+			}
+			if converted == nil {
+				continue
+			}
+
+			value := reflect.ValueOf(converted).Convert(indirect(field.Type))
+			if field.Type.Kind() == reflect.Ptr {
+				ptr := reflect.New(value.Type())
+				ptr.Elem().Set(value)
+				value = ptr
+			}
+			if dest := allocField(target, field.Index); dest.IsValid() {
+				dest.Set(value)
+			}
+		}
+		return issues
+	}
+
+	return []*issue{newIssue(
+		loc,
+		"form bodies cannot be decoded into %s",
+		types.UnsupportedMediaType,
+		target.Type(),
+	)}
+}
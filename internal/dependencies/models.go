@@ -1,11 +1,15 @@
 package dependencies
 
-import "reflect"
+import (
+	"reflect"
+	"slices"
+)
 
 type param struct {
 	I           int
 	Name        string
 	ReflectType reflect.Type
+	NameErr     error
 }
 
 type model struct {
@@ -27,6 +31,7 @@ func newModel(i int, name string, typ reflect.Type, rflTyp reflect.Type) *model
 }
 
 type dependant struct {
+	Params        int
 	PathParams    []*model
 	QueryParams   []*model
 	BodyParams    []*model
@@ -35,11 +40,21 @@ type dependant struct {
 	FormParams    []*model
 	ServiceParams []*model
 	ContextParams []*model
+	ClaimsParams  []*model
+	StructParams  []*structModel
+	Providers     []*providerModel
+	Injected      []*model
 	Request       *model
 	Response      *model
 	Websocket     *model
+	Stream        *model
 }
 
+// arguments holds the values bound for one request, indexed by the
+// position of the handler parameter. The cached dependant is shared across
+// requests and is never written to while serving.
+type arguments []reflect.Value
+
 func newDependant() *dependant {
 	return &dependant{
 		PathParams:    []*model{},
@@ -50,5 +65,46 @@ func newDependant() *dependant {
 		FormParams:    []*model{},
 		ServiceParams: []*model{},
 		ContextParams: []*model{},
+		ClaimsParams:  []*model{},
+		StructParams:  []*structModel{},
+		Providers:     []*providerModel{},
+		Injected:      []*model{},
+	}
+}
+
+func (d *dependant) hasBody() bool {
+	if len(d.BodyParams) > 0 || d.Stream != nil {
+		return true
+	}
+	for _, sm := range d.StructParams {
+		if sm.Body {
+			return true
+		}
+	}
+	return false
+}
+
+// needsBody reports whether binding d, or any provider it reaches, reads
+// the parsed request body.
+func (d *dependant) needsBody() bool {
+	for _, dep := range d.withProviders() {
+		if dep.hasBody() || len(dep.FormParams) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// withProviders returns d followed by the dependants of every provider it
+// reaches, each listed once.
+func (d *dependant) withProviders() []*dependant {
+	all := []*dependant{d}
+	for i := 0; i < len(all); i++ {
+		for _, pm := range all[i].Providers {
+			if !slices.Contains(all, pm.depend) {
+				all = append(all, pm.depend)
+			}
+		}
 	}
+	return all
 }
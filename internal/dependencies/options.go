@@ -0,0 +1,225 @@
+package dependencies
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AbrahamBass/swiftapi/internal/responses"
+	"github.com/AbrahamBass/swiftapi/internal/types"
+	"github.com/AbrahamBass/swiftapi/internal/validation"
+)
+
+// ParamTags holds struct-tag style options for the wrapper parameters of
+// one route, keyed by parameter name. They take the same keys a request
+// struct field does, e.g. `default:"1"`, `split:","` or
+// `validate:"min=1"`.
+type ParamTags map[string]reflect.StructTag
+
+// DefaultMultipartMemory is how much of a multipart body is held in
+// memory when no route or application setting overrides it.
+const DefaultMultipartMemory = 10 << 20
+
+// DefaultBodyLimit caps request bodies when no route, router or
+// application limit is set. A negative limit removes the cap.
+const DefaultBodyLimit = 32 << 20
+
+// RouteConfig carries the per-route settings a wrapper binds with. A
+// BodyLimit or Timeout of zero or less leaves the body size or the
+// handling time unlimited. Registry is the one of the application
+// serving the route.
+type RouteConfig struct {
+	Tags            ParamTags
+	BodyLimit       int64
+	MultipartMemory int64
+	Timeout         time.Duration
+	Errors          *responses.ErrorMapping
+	Registry        *Registry
+}
+
+// routeBinding is RouteConfig resolved once when the wrapper is built.
+type routeBinding struct {
+	options         map[string]fieldOptions
+	bodyLimit       int64
+	multipartMemory int64
+	timeout         time.Duration
+	errors          *responses.ErrorMapping
+	registry        *Registry
+}
+
+func (c RouteConfig) binding() *routeBinding {
+	memory := c.MultipartMemory
+	if memory <= 0 {
+		memory = DefaultMultipartMemory
+	}
+	limit := c.BodyLimit
+	switch {
+	case limit == 0:
+		limit = DefaultBodyLimit
+	case limit < 0:
+		limit = 0
+	}
+	return &routeBinding{
+		options:         c.Tags.options(),
+		bodyLimit:       limit,
+		multipartMemory: memory,
+		timeout:         c.Timeout,
+		errors:          c.Errors,
+		registry:        c.Registry,
+	}
+}
+
+type fieldOptions struct {
+	Default    string
+	HasDefault bool
+	Split      string
+	Validate   string
+}
+
+func parseOptions(tag reflect.StructTag) fieldOptions {
+	value, ok := tag.Lookup("default")
+	return fieldOptions{
+		Default:    value,
+		HasDefault: ok,
+		Split:      tag.Get("split"),
+		Validate:   tag.Get("validate"),
+	}
+}
+
+func (t ParamTags) options() map[string]fieldOptions {
+	opts := make(map[string]fieldOptions, len(t))
+	for name, tag := range t {
+		opts[name] = parseOptions(tag)
+	}
+	return opts
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
+
+// isMultiValue reports whether t collects every value of a key. Slice
+// types with a converter of their own, such as net.IP, take one value.
+func (reg *Registry) isMultiValue(t reflect.Type) bool {
+	t = indirect(t)
+	if t.Kind() != reflect.Slice {
+		return false
+	}
+	_, ok := reg.customConverter(t)
+	return !ok
+}
+
+// headerValues joins repeated headers into one value unless the target
+// collects every value into a slice.
+func (reg *Registry) headerValues(location string, typ reflect.Type, raws []string) []string {
+	if location != types.ParamLocationHeader || reg.isMultiValue(typ) || len(raws) < 2 {
+		return raws
+	}
+	return []string{strings.Join(raws, ", ")}
+}
+
+// inputValue turns the raw request values for one key into the target
+// type. Empty values count as missing unless the target is a string, a
+// default fills in a missing value, and a pointer target may be left
+// unset, which is reported by returning nil without issues. Slice targets
+// receive every value, each split on opts.Split when set.
+func (reg *Registry) inputValue(raws []string, typ reflect.Type, opts fieldOptions, loc []string) (interface{}, []*issue) {
+	target := indirect(typ)
+	multi := reg.isMultiValue(target)
+	elem := target
+	if multi {
+		elem = target.Elem()
+	}
+
+	values := make([]string, 0, len(raws))
+	for _, raw := range raws {
+		if opts.Split == "" {
+			values = append(values, raw)
+			continue
+		}
+		for _, part := range strings.Split(raw, opts.Split) {
+			values = append(values, strings.TrimSpace(part))
+		}
+	}
+
+	if elem.Kind() != reflect.String {
+		values = slices.DeleteFunc(values, func(v string) bool { return v == "" })
+	}
+
+	if len(values) == 0 {
+		switch {
+		case opts.HasDefault:
+			values = []string{opts.Default}
+			if opts.Split != "" {
+				values = strings.Split(opts.Default, opts.Split)
+			}
+		case typ.Kind() == reflect.Ptr:
+			return nil, nil
+		default:
+			return nil, []*issue{newIssue(loc, "field required", types.Missing)}
+		}
+	}
+
+	if !multi {
+		converted, err := reg.convertToType(values[0], target)
+		if err != nil {
+			return nil, []*issue{conversionIssue(loc, err)}
+		}
+		return reflect.ValueOf(converted).Convert(target).Interface(), nil
+	}
+
+	var issues []*issue
+	result := reflect.MakeSlice(target, len(values), len(values))
+	for i, value := range values {
+		converted, err := reg.convertToType(value, elem)
+		if err != nil {
+			issues = append(issues, conversionIssue(append(slices.Clip(loc), strconv.Itoa(i)), err))
+			continue
+		}
+		result.Index(i).Set(reflect.ValueOf(converted).Convert(elem))
+	}
+
+	if len(issues) > 0 {
+		return nil, issues
+	}
+	return result.Interface(), nil
+}
+
+func conversionIssue(loc []string, err error) *issue {
+	return newIssue(
+		loc,
+		"Conversion error: %v",
+		types.General,
+		err,
+	)
+}
+
+func (reg *Registry) checkDefault(name string, typ reflect.Type, opts fieldOptions) *ParamError {
+	if !opts.HasDefault {
+		return nil
+	}
+	if _, issues := reg.inputValue(nil, typ, opts, nil); len(issues) > 0 {
+		return newParamError(name, typ, fmt.Sprintf("default %q is not a valid %s", opts.Default, indirect(typ)))
+	}
+	return nil
+}
+
+// validateValue applies the validate rules of a bound value, along with
+// those of any structs nested in it, and reports failures as issues.
+func validateValue(v reflect.Value, tag string, loc []string) []*issue {
+	if !v.IsValid() {
+		return nil
+	}
+
+	var issues []*issue
+	for _, failure := range validation.Value(v, tag, loc) {
+		issues = append(issues, newIssue(failure.Loc, failure.Format, failure.Type, failure.Args...))
+	}
+	return issues
+}
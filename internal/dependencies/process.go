@@ -2,33 +2,31 @@ package dependencies
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"go/ast"
 	"go/parser"
-	"go/printer"
 	"go/token"
 	"io"
+	"mime"
 	"mime/multipart"
 	"net/http"
 	"net/url"
 	"reflect"
 	"runtime"
+	"slices"
 	"sort"
 	"strings"
-	"sync"
 
+	"github.com/AbrahamBass/swiftapi/internal/auth"
 	"github.com/AbrahamBass/swiftapi/internal/responses"
 	"github.com/AbrahamBass/swiftapi/internal/types"
+	"github.com/AbrahamBass/swiftapi/internal/validation"
 	"github.com/AbrahamBass/swiftapi/internal/ws"
 
-	"go.uber.org/zap"
-)
-
-var (
-	paramCache  sync.Map
-	dependCache sync.Map
+	"github.com/golang-jwt/jwt/v5"
 )
 
 type cacheKey struct {
@@ -44,134 +42,149 @@ func generateCacheKey(fn interface{}) cacheKey {
 	}
 }
 
-func analyzeFunctionWithCache(fn interface{}) ([]param, error) {
+func (reg *Registry) analyzeFunctionWithCache(fn interface{}) ([]param, error) {
 	key := generateCacheKey(fn)
 
-	if cached, exists := paramCache.Load(key); exists {
+	if cached, exists := reg.paramCache.Load(key); exists {
 		return cached.([]param), nil
 	}
 
-	params, err := analyzeFunction(fn)
+	params, err := reg.analyzeFunction(fn)
 	if err != nil {
 		return nil, err
 	}
 
-	paramCache.Store(key, params)
+	reg.paramCache.Store(key, params)
 	return params, nil
 }
 
-func analyzeDependenciesWithCache(fn interface{}) (*dependant, error) {
+func (reg *Registry) analyzeDependenciesWithCache(fn interface{}) (*dependant, error) {
+	if reflect.ValueOf(fn).Kind() != reflect.Func {
+		return nil, &HandlerError{
+			Func: funcName(fn),
+			Err:  fmt.Errorf("handler must be a function, got %T", fn),
+		}
+	}
+
 	key := generateCacheKey(fn)
 
-	if cached, exists := dependCache.Load(key); exists {
+	if cached, exists := reg.dependCache.Load(key); exists {
 		return cached.(*dependant), nil
 	}
 
-	params, err := analyzeFunctionWithCache(fn)
+	params, err := reg.analyzeFunctionWithCache(fn)
 	if err != nil {
-		return nil, err
+		return nil, &HandlerError{Func: funcName(fn), Err: err}
 	}
 
-	depend, err := getDependant(params)
-	if err != nil {
-		return nil, err
+	depend, problems := reg.getDependant(params, nil)
+	if len(problems) > 0 {
+		return nil, &HandlerError{Func: funcName(fn), Params: problems}
 	}
 
-	dependCache.Store(key, depend)
+	reg.dependCache.Store(key, depend)
 	return depend, nil
 }
 
-func analyzeFunction(fn interface{}) ([]param, error) {
+func (reg *Registry) analyzeFunction(fn interface{}) ([]param, error) {
 	fnType := reflect.TypeOf(fn)
 	if fnType.Kind() != reflect.Func {
-		return nil, fmt.Errorf("se requiere una función")
+		return nil, fmt.Errorf("handler must be a function, got %T", fn)
 	}
 
-	funcStr, err := getFunctionSource(fn)
-	if err != nil {
-		return nil, err
+	names, nameErr := reg.paramNames(fn)
+	if nameErr == nil && len(names) != fnType.NumIn() {
+		return nil, fmt.Errorf("%d parameter names known for a function taking %d parameters", len(names), fnType.NumIn())
 	}
 
-	return extractParamsWithTypes(funcStr, fnType)
-}
+	params := make([]param, 0, fnType.NumIn())
+	for i := 0; i < fnType.NumIn(); i++ {
+		p := param{
+			I:           i,
+			Name:        fmt.Sprintf("arg%d", i),
+			ReflectType: fnType.In(i),
+			NameErr:     nameErr,
+		}
 
-func getFunctionSource(fn interface{}) (string, error) {
-	pc := runtime.FuncForPC(reflect.ValueOf(fn).Pointer())
-	file, line := pc.FileLine(pc.Entry())
+		if nameErr == nil {
+			if names[i] == "" || names[i] == "_" {
+				p.NameErr = fmt.Errorf("parameter %d has no name", i)
+			} else {
+				p.Name = names[i]
+			}
+		}
 
-	fset := token.NewFileSet()
-	parsedFile, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
-	if err != nil {
-		return "", err
+		params = append(params, p)
 	}
 
-	var funcDecl *ast.FuncDecl
-	ast.Inspect(parsedFile, func(n ast.Node) bool {
-		if fd, ok := n.(*ast.FuncDecl); ok && fset.Position(fd.Pos()).Line <= line && fset.Position(fd.End()).Line >= line {
-			funcDecl = fd
-			return false
-		}
-		return true
-	})
+	return params, nil
+}
 
-	if funcDecl == nil {
-		return "", fmt.Errorf("función no encontrada en el AST")
+// paramNames returns the declared parameter names of fn, preferring those
+// given to RegisterParams and falling back to the Go source when lookup
+// is enabled.
+func (reg *Registry) paramNames(fn interface{}) ([]string, error) {
+	if names, ok := registeredParams(fn); ok {
+		return names, nil
 	}
 
-	var buf bytes.Buffer
-	if err := printer.Fprint(&buf, fset, funcDecl); err != nil {
-		return "", err
+	if !reg.sourceLookup.Load() {
+		return nil, fmt.Errorf("source lookup is disabled")
 	}
 
-	return buf.String(), nil
+	return sourceParamNames(fn)
 }
 
-func extractParamsWithTypes(funcStr string, fnType reflect.Type) ([]param, error) {
-	wrapped := "package main\n\n" + funcStr
+func sourceParamNames(fn interface{}) ([]string, error) {
+	pc := runtime.FuncForPC(reflect.ValueOf(fn).Pointer())
+	file, line := pc.FileLine(pc.Entry())
+
+	if strings.HasSuffix(pc.Name(), "-fm") {
+		return nil, fmt.Errorf("method value %s has no source position", pc.Name())
+	}
+
 	fset := token.NewFileSet()
-	file, err := parser.ParseFile(fset, "", wrapped, parser.ParseComments)
+	parsedFile, err := parser.ParseFile(fset, file, nil, 0)
 	if err != nil {
 		return nil, err
 	}
 
-	var funcDecl *ast.FuncDecl
-	for _, decl := range file.Decls {
-		if fd, ok := decl.(*ast.FuncDecl); ok {
-			funcDecl = fd
-			break
+	// The innermost function spanning the entry line is the one we want;
+	// this picks a closure over the declaration that encloses it.
+	var funcType *ast.FuncType
+	ast.Inspect(parsedFile, func(n ast.Node) bool {
+		if n == nil || fset.Position(n.Pos()).Line > line || fset.Position(n.End()).Line < line {
+			return false
 		}
-	}
+		switch fn := n.(type) {
+		case *ast.FuncDecl:
+			funcType = fn.Type
+		case *ast.FuncLit:
+			funcType = fn.Type
+		}
+		return true
+	})
 
-	if funcDecl == nil {
-		return nil, fmt.Errorf("declaración de función no encontrada")
+	if funcType == nil {
+		return nil, fmt.Errorf("function %s not found in %s", pc.Name(), file)
 	}
 
-	params := make([]param, 0)
-	if funcDecl.Type.Params != nil {
-		paramIndex := 0
-		for _, field := range funcDecl.Type.Params.List {
-
-			for _, name := range field.Names {
-				if paramIndex >= fnType.NumIn() {
-					return nil, fmt.Errorf("discrepancia en número de parámetros")
-				}
-
-				params = append(params, param{
-					I:           paramIndex,
-					Name:        name.Name,
-					ReflectType: fnType.In(paramIndex),
-				})
-				paramIndex++
-			}
+	names := make([]string, 0)
+	for _, field := range funcType.Params.List {
+		if len(field.Names) == 0 {
+			names = append(names, "")
+		}
+		for _, name := range field.Names {
+			names = append(names, name.Name)
 		}
 	}
 
-	return params, nil
+	return names, nil
 }
 
 func generic(t reflect.Type) (string, reflect.Type, error) {
 	if t.Kind() != reflect.Struct {
-		return "", nil, fmt.Errorf("el tipo %s no es una estructura", t)
+		return "", nil, fmt.Errorf("type %s is not a struct", t)
 	}
 
 	baseName := ""
@@ -183,24 +196,50 @@ func generic(t reflect.Type) (string, reflect.Type, error) {
 
 	field, ok := t.FieldByName("Value")
 	if !ok {
-		return "", nil, fmt.Errorf("el tipo %s no tiene campo 'Value'", baseName)
+		return "", nil, fmt.Errorf("type %s has no Value field", baseName)
 	}
 
 	if field.Type == nil {
-		return "", nil, fmt.Errorf("tipo genérico inválido en campo Value")
+		return "", nil, fmt.Errorf("invalid generic type in Value field")
 	}
 
 	return baseName, field.Type, nil
 }
 
-func getDependant(params []param) (*dependant, error) {
+// getDependant sorts params by how they are bound. chain lists the
+// providers whose parameters are being analyzed, outermost first.
+func (reg *Registry) getDependant(params []param, chain []reflect.Type) (*dependant, []*ParamError) {
 	depends := newDependant()
+	depends.Params = len(params)
 
+	var problems []*ParamError
 	for _, p := range params {
+		if injectable(p.ReflectType) {
+			depends.Injected = append(depends.Injected, newModel(p.I, p.Name, p.ReflectType, p.ReflectType))
+			continue
+		}
+
+		if structType, ok := requestStruct(p.ReflectType); ok {
+			sm, fieldProblems := reg.newStructModel(p, structType)
+			problems = append(problems, fieldProblems...)
+
+			if sm.Body && (depends.hasBody() || len(depends.FormParams) > 0) {
+				problems = append(problems, newParamError(p.Name, p.ReflectType, "only one body parameter is allowed and it cannot be mixed with form parameters"))
+			}
+
+			depends.StructParams = append(depends.StructParams, sm)
+			continue
+		}
+
 		baseName, fieldType, err := generic(p.ReflectType)
 
 		if err != nil {
-			return nil, err
+			problems = append(problems, newParamError(
+				p.Name,
+				p.ReflectType,
+				"must be a request struct, one of the Query, Pathway, Signal, Crumb, Body, Silk, Dependency, Scope or Claims wrappers, or a context.Context, http.ResponseWriter, *http.Request, RequestScope, *Principal or jwt.Claims",
+			))
+			continue
 		}
 
 		model := &model{
@@ -210,76 +249,182 @@ func getDependant(params []param) (*dependant, error) {
 			ReflectType: p.ReflectType,
 		}
 		tag := types.TagType(baseName)
-		if !addNonFieldParamToDependency(
+
+		if p, ok := reg.lookupProvider(fieldType); ok && tag == types.TagService {
+			pm, problem := reg.newProviderModel(model, p, chain)
+			if problem != nil {
+				problems = append(problems, problem)
+				continue
+			}
+			depends.Providers = append(depends.Providers, pm)
+			continue
+		}
+
+		if addNonFieldParamToDependency(
 			model,
 			depends,
 		) {
-			addParamToFields(
-				tag,
-				model,
-				depends,
-			)
+			continue
 		}
 
+		if p.NameErr != nil && needsName(tag) {
+			problems = append(problems, newParamError(
+				p.Name,
+				p.ReflectType,
+				fmt.Sprintf("parameter name is unknown (%v); register it with RegisterParams or generate it with swiftapi-gen", p.NameErr),
+			))
+			continue
+		}
+
+		if problem := reg.addParamToFields(
+			tag,
+			model,
+			depends,
+		); problem != nil {
+			problems = append(problems, problem)
+		}
 	}
 
-	return depends, nil
+	return depends, problems
 }
 
 func addNonFieldParamToDependency(
 	field *model,
 	dependant *dependant,
 ) bool {
-	if field.Type == reflect.TypeOf(&http.Request{}) {
+	switch field.Type {
+	case requestType:
 		dependant.Request = field
 		return true
-	} else if field.Type == reflect.TypeOf((*ws.WebsocketManager)(nil)) {
+	case responseWriterType:
+		dependant.Response = field
+		return true
+	case wsManagerType:
 		dependant.Websocket = field
 		return true
 	}
 	return false
 }
 
-func addParamToFields(
+var (
+	contextType        = reflect.TypeOf((*context.Context)(nil)).Elem()
+	responseWriterType = reflect.TypeOf((*http.ResponseWriter)(nil)).Elem()
+	wsManagerType      = reflect.TypeOf((*ws.WebsocketManager)(nil))
+	claimsType         = reflect.TypeOf((*jwt.Claims)(nil)).Elem()
+	mapClaimsType      = reflect.TypeOf(jwt.MapClaims{})
+	principalType      = reflect.TypeOf((*auth.Principal)(nil))
+)
+
+// injectable reports whether a handler may declare a parameter of type t
+// directly, without a wrapper.
+func injectable(t reflect.Type) bool {
+	switch t {
+	case contextType, responseWriterType, requestType, reqScopeType, wsManagerType, claimsType, mapClaimsType, principalType:
+		return true
+	}
+	return false
+}
+
+// injectedValue returns the value of a plain parameter of type t. The
+// context is the one of the request being served, canceled when the
+// client goes away or the route times out. Claims are those the JWT
+// middleware verified and are left nil on routes without it, as is the
+// principal of a request no authentication scheme accepted.
+func injectedValue(t reflect.Type, state *requestState, w *responses.ResponseWriter, webscoketManeger *ws.WebsocketManager) reflect.Value {
+	var value interface{}
+	switch t {
+	case contextType:
+		value = state.req.Context()
+	case responseWriterType:
+		value = w.Writer()
+	case requestType:
+		value = state.req
+	case reqScopeType:
+		value = state.scope
+	case wsManagerType:
+		value = webscoketManeger
+	case claimsType, mapClaimsType:
+		value = state.req.Context().Value("claims")
+	case principalType:
+		if p, ok := auth.FromRequest(state.req); ok {
+			value = p
+		}
+	}
+
+	if value == nil || !reflect.TypeOf(value).AssignableTo(t) {
+		return reflect.Value{}
+	}
+	return reflect.ValueOf(value)
+}
+
+func (reg *Registry) addParamToFields(
 	tag types.TagType,
 	field *model,
 	dependant *dependant,
-) {
+) *ParamError {
 	switch tag {
 	case types.TagQuery:
 		dependant.QueryParams = append(dependant.QueryParams, field)
+		return reg.checkConvertible(field, tag)
 	case types.TagPath:
 		dependant.PathParams = append(dependant.PathParams, field)
+		return reg.checkConvertible(field, tag)
 	case types.TagBody:
 
-		if len(dependant.BodyParams) > 0 {
-			panic("only one body parameter is allowed")
+		if dependant.Stream != nil {
+			return newParamError(field.Name, field.ReflectType, "cannot mix a FileStream with body parameters")
+		}
+
+		if dependant.hasBody() {
+			return newParamError(field.Name, field.ReflectType, "only one body parameter is allowed")
 		}
 
 		if len(dependant.FormParams) > 0 {
-			panic("cannot mix body and form parameters")
+			return newParamError(field.Name, field.ReflectType, "cannot mix body and form parameters")
 		}
 
 		dependant.BodyParams = append(dependant.BodyParams, field)
+		if err := validation.CheckType(field.Type); err != nil {
+			return newParamError(field.Name, field.ReflectType, err.Error())
+		}
 	case types.TagCookie:
 		dependant.CookieParams = append(dependant.CookieParams, field)
+		return reg.checkConvertible(field, tag)
 	case types.TagHeader:
 		dependant.HeaderParams = append(dependant.HeaderParams, field)
+		return reg.checkConvertible(field, tag)
 	case types.TagForm:
 
-		if len(dependant.BodyParams) > 0 {
-			panic("cannot add form parameters when body is already present")
+		if field.Type == fileStreamType {
+			if dependant.hasBody() || len(dependant.FormParams) > 0 {
+				return newParamError(field.Name, field.ReflectType, "a FileStream reads the whole body and cannot be mixed with body or form parameters")
+			}
+			dependant.Stream = field
+			return nil
+		}
+
+		if dependant.hasBody() {
+			return newParamError(field.Name, field.ReflectType, "cannot mix body and form parameters")
 		}
 
 		dependant.FormParams = append(dependant.FormParams, field)
+		if isUpload(field.Type) {
+			return nil
+		}
+		return reg.checkConvertible(field, tag)
 	case types.TagService:
 		dependant.ServiceParams = append(dependant.ServiceParams, field)
 	case types.TagContext:
 		dependant.ContextParams = append(dependant.ContextParams, field)
+	case types.TagClaims:
+		dependant.ClaimsParams = append(dependant.ClaimsParams, field)
+	default:
+		return newParamError(field.Name, field.ReflectType, fmt.Sprintf("unknown parameter wrapper %q", tag))
 	}
+	return nil
 }
 
-func safeSetField(field *model, value interface{}) error {
+func safeSetField(args arguments, field *model, value interface{}) error {
 
 	genericType := field.ReflectType.Field(0).Type
 
@@ -316,287 +461,310 @@ func safeSetField(field *model, value interface{}) error {
 	}
 
 	instance.Field(0).Set(val)
-	field.Default = instance.Interface()
+	args[field.I] = instance
 
 	return nil
 }
 
-func extractBody(r *http.Request) (interface{}, []*issue) {
+func extractBody(r *http.Request, binding *routeBinding) (interface{}, []*issue) {
 	var issues []*issue
 	var body interface{}
 	loc := []string{"body"}
 
-	if r.Body == nil || r.Body == http.NoBody {
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
 		return nil, issues
 	}
 
-	contentType := r.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
 
-	rawBody, err := io.ReadAll(r.Body)
-	if err != nil {
-		issues = append(issues, newIssue(
-			loc,
-			"Failed to read request body",
-			types.BodyRead,
-		))
-		return nil, issues
-	}
-
-	r.Body = io.NopCloser(bytes.NewBuffer(rawBody))
-
-	if len(rawBody) == 0 {
-		return nil, issues
-	}
-
-	switch {
-	case strings.Contains(contentType, "multipart/form-data"):
-		if err := r.ParseMultipartForm(10 << 20); err != nil {
+	switch types.MediaType(mediaType) {
+	case types.MultipartForm:
+		// Parsed straight from the connection: parts beyond the memory
+		// threshold go to temporary files rather than a buffered copy.
+		if err := r.ParseMultipartForm(binding.multipartMemory); err != nil {
+			if tooLarge(err) {
+				return nil, []*issue{bodyTooLarge(loc, binding.bodyLimit)}
+			}
 			issues = append(issues, newIssue(
 				loc,
-				fmt.Sprintf("Failed to parse multipart form: %s", err.Error()),
+				"Failed to parse multipart form: %s",
 				types.Multipart,
+				err.Error(),
 			))
 		} else {
 			body = r.MultipartForm
 		}
-	case contentType == "application/x-www-form-urlencoded":
+	case types.ApplicationForm:
 		if err := r.ParseForm(); err != nil {
+			if tooLarge(err) {
+				return nil, []*issue{bodyTooLarge(loc, binding.bodyLimit)}
+			}
 			issues = append(issues, newIssue(
 				loc,
-				fmt.Sprintf("Failed to parse form data: %s", err.Error()),
+				"Failed to parse form data: %s",
 				types.URLEncoded,
+				err.Error(),
 			))
 		} else {
 			body = r.PostForm
 		}
 	default:
-		body = rawBody
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			if tooLarge(err) {
+				return nil, []*issue{bodyTooLarge(loc, binding.bodyLimit)}
+			}
+			issues = append(issues, newIssue(
+				loc,
+				"Failed to read request body",
+				types.BodyRead,
+			))
+			return nil, issues
+		}
+
+		r.Body = io.NopCloser(bytes.NewBuffer(data))
+
+		if len(data) == 0 {
+			return nil, issues
+		}
+		body = &rawBody{data: data, mediaType: mediaType}
 	}
 
 	return body, issues
 }
 
-func processInputFields(fields []*model, input map[string]string, location string) []*issue {
+func tooLarge(err error) bool {
+	var maxErr *http.MaxBytesError
+	return errors.As(err, &maxErr)
+}
+
+func bodyTooLarge(loc []string, limit int64) *issue {
+	return newIssue(
+		loc,
+		"request body exceeds the limit of %d bytes",
+		types.BodyTooLarge,
+		limit,
+	)
+}
+
+func (reg *Registry) processInputFields(args arguments, fields []*model, input map[string][]string, location string, options map[string]fieldOptions) []*issue {
 	var issues []*issue
 
 	for _, field := range fields {
-		rawValue, exists := input[field.Name]
 		loc := []string{location, field.Name}
+		raws := reg.headerValues(location, field.Type, input[field.Name])
 
-		if !exists {
-			issues = append(issues, newIssue(
-				loc,
-				"field required",
-				types.Missing,
-			))
+		convertedValue, fieldIssues := reg.inputValue(raws, field.Type, options[field.Name], loc)
+		if len(fieldIssues) > 0 {
+			issues = append(issues, fieldIssues...)
 			continue
 		}
-
-		convertedValue, err := convertToType(rawValue, field.Type)
-		if err != nil {
-			issues = append(issues, newIssue(
-				loc,
-				fmt.Sprintf("Conversion error: %v", err),
-				types.General,
-			))
+		if convertedValue == nil {
 			continue
 		}
 
-		if err := safeSetField(field, convertedValue); err != nil {
+		if err := safeSetField(args, field, convertedValue); err != nil {
 			issues = append(issues, newIssue(loc, err.Error(), types.TypeError))
+			continue
 		}
+
+		issues = append(issues, validateValue(args[field.I].Field(0), options[field.Name].Validate, loc)...)
 	}
 
 	return issues
 }
 
-func parseParams(req *http.Request) map[string]string {
-	params := make(map[string]string)
+func parseParams(req *http.Request) map[string][]string {
+	params := make(map[string][]string)
 	if rv := req.Context().Value(1); rv != nil {
 		mapParams, ok := rv.(map[string]string)
 		if ok {
 			for k, v := range mapParams {
-				params[k] = v
+				params[k] = []string{v}
 			}
 		}
 	}
 	return params
 }
 
-func parseQuery(req *http.Request) map[string]string {
-	queryParams := req.URL.Query()
-	queryMap := make(map[string]string)
-	for key := range queryParams {
-		queryMap[key] = queryParams.Get(key)
-	}
-	return queryMap
+func parseQuery(req *http.Request) map[string][]string {
+	return req.URL.Query()
 }
 
-func parseHeaders(r *http.Request) map[string]string {
-	headers := make(map[string]string)
+func parseHeaders(r *http.Request) map[string][]string {
+	headers := make(map[string][]string)
 	for key, values := range r.Header {
 		if len(values) > 0 {
 			camelCaseKey := formatHeaderKey(key)
-			headers[camelCaseKey] = strings.Join(values, ", ")
+			headers[camelCaseKey] = append(headers[camelCaseKey], values...)
 		}
 	}
 	return headers
 }
 
-func parseCookies(r *http.Request) map[string]string {
-	cookies := make(map[string]string)
+func parseCookies(r *http.Request) map[string][]string {
+	cookies := make(map[string][]string)
 	for _, cookie := range r.Cookies() {
-		cookies[cookie.Name] = cookie.Value
+		cookies[cookie.Name] = append(cookies[cookie.Name], cookie.Value)
 	}
 	return cookies
 }
 
-func handleUploadType(field *model, b map[string][]*multipart.FileHeader, loc []string) *issue {
+var (
+	uploadFileType  = reflect.TypeOf((*types.UploadFile)(nil)).Elem()
+	uploadFilesType = reflect.SliceOf(uploadFileType)
+	fileStreamType  = reflect.TypeOf(types.FileStream{})
+)
+
+func isUpload(t reflect.Type) bool {
+	return t == uploadFileType || t == uploadFilesType
+}
+
+func handleUploadType(args arguments, field *model, b map[string][]*multipart.FileHeader, loc []string) []*issue {
 	files, ok := b[field.Name]
 	if !ok || len(files) == 0 {
-		return newIssue(loc, field.Name, types.Missing)
+		return []*issue{newIssue(loc, "file required", types.Missing)}
+	}
+
+	var value interface{} = types.UploadFile(files[0])
+	if field.Type == uploadFilesType {
+		uploads := make([]types.UploadFile, len(files))
+		for i, file := range files {
+			uploads[i] = file
+		}
+		value = uploads
 	}
 
-	if err := safeSetField(field, files[0]); err != nil {
-		return newIssue(loc, err.Error(), types.TypeError)
+	if err := safeSetField(args, field, value); err != nil {
+		return []*issue{newIssue(loc, err.Error(), types.TypeError)}
 	}
 
 	return nil
 }
 
-func handleMultipartField(field *model, b map[string][]string, loc []string) *issue {
-	values, ok := b[field.Name]
-	if !ok || len(values) == 0 {
-		return newIssue(loc, field.Name, types.Missing)
-	}
-
-	convertedValue, err := convertToType(values[0], field.Type)
-	if err != nil {
-		return newIssue(loc, err.Error(), types.General)
+func (reg *Registry) handleMultipartField(args arguments, field *model, b map[string][]string, loc []string, opts fieldOptions) []*issue {
+	convertedValue, issues := reg.inputValue(b[field.Name], field.Type, opts, loc)
+	if len(issues) > 0 || convertedValue == nil {
+		return issues
 	}
 
-	if err := safeSetField(field, convertedValue); err != nil {
-		return newIssue(loc, err.Error(), types.TypeError)
+	if err := safeSetField(args, field, convertedValue); err != nil {
+		return []*issue{newIssue(loc, err.Error(), types.TypeError)}
 	}
 
-	return nil
+	return validateValue(args[field.I].Field(0), opts.Validate, loc)
 }
 
-func parseMultipart(field *model, body interface{}) *issue {
+func (reg *Registry) parseMultipart(args arguments, field *model, body interface{}, opts fieldOptions) []*issue {
 	loc := []string{"form", field.Name}
 
 	if body == nil {
-		return newIssue(
+		if opts.HasDefault || field.Type.Kind() == reflect.Ptr {
+			return reg.handleMultipartField(args, field, nil, loc, opts)
+		}
+		return []*issue{newIssue(
 			loc,
 			"form data is required",
 			types.Missing,
-		)
+		)}
 	}
 
 	switch b := body.(type) {
 	case *multipart.Form:
-		if field.Type == reflect.TypeOf((*types.UploadFile)(nil)).Elem() {
-			return handleUploadType(field, b.File, loc)
+		if isUpload(field.Type) {
+			return handleUploadType(args, field, b.File, loc)
 		} else {
-			return handleMultipartField(field, b.Value, loc)
+			return reg.handleMultipartField(args, field, b.Value, loc, opts)
 		}
 	case url.Values:
-		return handleMultipartField(field, b, loc)
+		return reg.handleMultipartField(args, field, b, loc, opts)
 	default:
-		return newIssue(
+		return []*issue{newIssue(
 			loc,
-			fmt.Sprintf("Unsupported form type: %T", body),
+			"Unsupported form type: %T",
 			types.UnsupportedType,
-		)
+			body,
+		)}
+	}
+}
+
+func parseStream(args arguments, field *model, req *http.Request) []*issue {
+	loc := []string{"form", field.Name}
+
+	reader, err := req.MultipartReader()
+	if err != nil {
+		return []*issue{newIssue(
+			loc,
+			"a %s body is required: %v",
+			types.UnsupportedMediaType,
+			types.MultipartForm, err,
+		)}
+	}
+
+	if err := safeSetField(args, field, types.FileStream{Reader: reader}); err != nil {
+		return []*issue{newIssue(loc, err.Error(), types.TypeError)}
 	}
+	return nil
 }
 
-func processMultipart(modelField []*model, body interface{}) []*issue {
+func (reg *Registry) processMultipart(args arguments, modelField []*model, body interface{}, options map[string]fieldOptions) []*issue {
 	var issues []*issue
 	for _, field := range modelField {
-		if issue := parseMultipart(field, body); issue != nil {
-			issues = append(issues, issue)
-		}
+		issues = append(issues, reg.parseMultipart(args, field, body, options[field.Name])...)
 	}
 	return issues
 }
 
-func parseBody(field *model, body interface{}) *issue {
+func (reg *Registry) parseBody(args arguments, field *model, body interface{}) []*issue {
 	loc := []string{"body", field.Name}
 
 	if body == nil {
-		return newIssue(
+		return []*issue{newIssue(
 			loc,
 			"request body is required",
 			types.Missing,
-		)
-	}
-
-	b, ok := body.([]byte)
-	if !ok {
-		return newIssue(
-			loc,
-			fmt.Sprintf("invalid body type: %T", body),
-			types.InvalidType,
-		)
-	}
-
-	if len(b) == 0 {
-		return newIssue(
-			loc,
-			"body cannot be empty",
-			types.Empty,
-		)
+		)}
 	}
 
-	switch field.Type.Kind() {
-	case reflect.Slice:
-		return handleSliceType(field, b, loc)
-	case reflect.String:
-		return handleStringType(field, b, loc)
-	case reflect.Struct, reflect.Ptr:
-		return handleStructType(field, b, loc)
+	var decoded reflect.Value
+	switch b := body.(type) {
+	case *rawBody:
+		switch {
+		case field.Type == reflect.TypeOf([]byte(nil)):
+			decoded = reflect.ValueOf(b.data)
+		case field.Type.Kind() == reflect.String:
+			decoded = reflect.ValueOf(string(b.data)).Convert(field.Type)
+		default:
+			decoded = reflect.New(indirect(field.Type))
+			if bodyIssue := reg.decodeBody(b, decoded.Interface(), loc); bodyIssue != nil {
+				return []*issue{bodyIssue}
+			}
+		}
+	case url.Values:
+		decoded = reflect.New(indirect(field.Type))
+		if issues := reg.decodeValues(b, decoded.Interface(), loc); len(issues) > 0 {
+			return issues
+		}
+	case *multipart.Form:
+		decoded = reflect.New(indirect(field.Type))
+		if issues := reg.decodeValues(b.Value, decoded.Interface(), loc); len(issues) > 0 {
+			return issues
+		}
 	default:
-		return newIssue(
+		return []*issue{newIssue(
 			loc,
-			fmt.Sprintf("unsupported body type: %s", field.Type.Kind()),
-			types.Unsupported,
-		)
-	}
-}
-
-func handleSliceType(field *model, b []byte, loc []string) *issue {
-	if err := safeSetField(field, b); err != nil {
-		return newIssue(loc, err.Error(), types.TypeError)
-	}
-	return nil
-}
-
-func handleStringType(field *model, b []byte, loc []string) *issue {
-	str := string(b)
-	if err := safeSetField(field, str); err != nil {
-		return newIssue(loc, err.Error(), types.TypeError)
-	}
-	return nil
-}
-
-func handleStructType(field *model, b []byte, loc []string) *issue {
-	targetType := field.Type
-	if targetType.Kind() == reflect.Ptr {
-		targetType = targetType.Elem()
-	}
-
-	decoded := reflect.New(targetType).Interface()
-	if err := json.Unmarshal(b, decoded); err != nil {
-		return parseJSONError(err, loc)
+			"invalid body type: %T",
+			types.InvalidType,
+			body,
+		)}
 	}
 
-	if field.Type.Kind() != reflect.Ptr {
-		decoded = reflect.ValueOf(decoded).Elem().Interface()
+	if decoded.Kind() == reflect.Ptr && field.Type.Kind() != reflect.Ptr {
+		decoded = decoded.Elem()
 	}
 
-	if err := safeSetField(field, decoded); err != nil {
-		return newIssue(loc, err.Error(), types.TypeError)
+	if err := safeSetField(args, field, decoded.Interface()); err != nil {
+		return []*issue{newIssue(loc, err.Error(), types.TypeError)}
 	}
 
 	return nil
@@ -611,18 +779,19 @@ func parseJSONError(err error, loc []string) *issue {
 	case errors.As(err, &syntaxErr):
 		return newIssue(
 			loc,
-			fmt.Sprintf("Invalid JSON syntax at position %d: %v", syntaxErr.Offset, syntaxErr.Error()),
+			"Invalid JSON syntax at position %d: %v",
 			types.Syntax,
+			syntaxErr.Offset, syntaxErr.Error(),
 		)
 	case errors.As(err, &typeErr):
+		if typeErr.Field != "" {
+			loc = append(slices.Clip(loc), strings.Split(typeErr.Field, ".")...)
+		}
 		return newIssue(
 			loc,
-			fmt.Sprintf("Type mismatch at %s (expected %s, got %s)",
-				typeErr.Field,
-				typeErr.Type,
-				typeErr.Value,
-			),
+			"Type mismatch at %s (expected %s, got %s)",
 			types.JSONType,
+			typeErr.Field, typeErr.Type, typeErr.Value,
 		)
 	case errors.As(err, &unmarshalErr):
 		return newIssue(
@@ -633,36 +802,38 @@ func parseJSONError(err error, loc []string) *issue {
 	default:
 		return newIssue(
 			loc,
-			fmt.Sprintf("JSON decoding error: %v", err),
+			"JSON decoding error: %v",
 			types.Target,
+			err,
 		)
 	}
 }
 
-func processBody(modelFiled []*model, body interface{}) []*issue {
+func (reg *Registry) processBody(args arguments, modelFiled []*model, body interface{}, options map[string]fieldOptions) []*issue {
 	var issues []*issue
 	for _, field := range modelFiled {
-		if issue := parseBody(field, body); issue != nil {
-			issues = append(issues, issue)
+		if bodyIssues := reg.parseBody(args, field, body); len(bodyIssues) > 0 {
+			issues = append(issues, bodyIssues...)
+			continue
 		}
+
+		issues = append(issues, validateValue(args[field.I].Field(0), options[field.Name].Validate, []string{"body", field.Name})...)
 	}
 	return issues
 }
 
-func parseService(dig types.IDigContainer, logger *zap.Logger, field *model) *issue {
+func parseService(args arguments, services *Services, state *requestState, field *model) (*issue, error) {
 	loc := []string{"service", field.Name}
-	instance, err := resolveDependency(dig, field.Type)
+	instance, err := services.resolve(field.Type, state)
 	if err != nil {
-		logger.Fatal("🚨 Failed to resolve dependency",
-			zap.Error(err),
-		)
+		return nil, err
 	}
 
-	if err := safeSetField(field, instance); err != nil {
-		return newIssue(loc, err.Error(), types.TypeError)
+	if err := safeSetField(args, field, instance.Interface()); err != nil {
+		return newIssue(loc, err.Error(), types.TypeError), nil
 	}
 
-	return nil
+	return nil, nil
 }
 
 func resolveDependency(container types.IDigContainer, targetType reflect.Type) (interface{}, error) {
@@ -686,56 +857,84 @@ func resolveDependency(container types.IDigContainer, targetType reflect.Type) (
 	return result, nil
 }
 
-func processService(dig types.IDigContainer, logger *zap.Logger, modelField []*model) []*issue {
+func processService(args arguments, services *Services, state *requestState, modelField []*model) ([]*issue, error) {
 	var issues []*issue
 	for _, field := range modelField {
-		if issue := parseService(dig, logger, field); issue != nil {
+		issue, err := parseService(args, services, state, field)
+		if err != nil {
+			return nil, err
+		}
+		if issue != nil {
 			issues = append(issues, issue)
 		}
 	}
-	return issues
+	return issues, nil
 }
 
-func parseContext(field *model, req *http.Request) *issue {
+func parseContext(args arguments, field *model, req *http.Request) *issue {
 	loc := []string{"context", field.Name}
 	value := req.Context().Value(field.Name)
 	if value != nil {
-		if err := safeSetField(field, value); err != nil {
+		if err := safeSetField(args, field, value); err != nil {
 			return newIssue(loc, err.Error(), types.TypeError)
 		}
 		return nil
 	}
 	return newIssue(
 		loc,
-		fmt.Sprintf("missing required context parameter: %s", field.Name),
+		"missing required context parameter: %s",
 		types.Missing,
+		field.Name,
 	)
 }
 
-func processContext(modelField []*model, req *http.Request) []*issue {
+// parseClaims decodes the claims of the principal the request was
+// authenticated as into the Claims parameter field.
+func parseClaims(args arguments, field *model, req *http.Request) *issue {
+	loc := []string{"claims"}
+
+	p, ok := auth.FromRequest(req)
+	if !ok {
+		return newIssue(loc, "authentication required", types.Unauthenticated)
+	}
+
+	value := reflect.New(field.Type)
+	if err := p.Decode(value.Interface()); err != nil {
+		return newIssue(loc, "claims do not match %s: %v", types.InvalidClaims, field.Type, err)
+	}
+
+	instance := reflect.New(field.ReflectType).Elem()
+	instance.Field(0).Set(value.Elem())
+	args[field.I] = instance
+	return nil
+}
+
+func processContext(args arguments, modelField []*model, req *http.Request) []*issue {
 	var issues []*issue
 	for _, field := range modelField {
-		if err := parseContext(field, req); err != nil {
+		if err := parseContext(args, field, req); err != nil {
 			issues = append(issues, err)
 		}
 	}
 	return issues
 }
 
-func solveDependant(
+func (reg *Registry) solveDependant(
 	webscoketManeger *ws.WebsocketManager,
-	dig types.IDigContainer,
-	logger *zap.Logger,
-	req *http.Request,
+	services *Services,
+	state *requestState,
 	w *responses.ResponseWriter,
 	dependant *dependant,
 	body interface{},
-) []*issue {
+	options map[string]fieldOptions,
+) (arguments, []*issue, error) {
+	req := state.req
 	var issues []*issue
+	args := make(arguments, dependant.Params)
 
 	paramProcessors := []struct {
 		params    []*model
-		extractor func(*http.Request) map[string]string
+		extractor func(*http.Request) map[string][]string
 		location  string
 	}{
 		{dependant.PathParams, parseParams, types.ParamLocationPath},
@@ -750,20 +949,33 @@ func solveDependant(
 		}
 
 		values := processor.extractor(req)
-		paramIssues := processInputFields(
+		paramIssues := reg.processInputFields(args,
 			processor.params,
 			values,
 			processor.location,
+			options,
 		)
 		issues = append(issues, paramIssues...)
 	}
 
+	if len(dependant.StructParams) > 0 {
+		issues = append(
+			issues,
+			reg.processStructs(args,
+				dependant.StructParams,
+				req,
+				body,
+			)...,
+		)
+	}
+
 	if dependant.FormParams != nil {
 		issues = append(
 			issues,
-			processMultipart(
+			reg.processMultipart(args,
 				dependant.FormParams,
 				body,
+				options,
 			)...,
 		)
 	}
@@ -771,36 +983,62 @@ func solveDependant(
 	if dependant.BodyParams != nil {
 		issues = append(
 			issues,
-			processBody(
+			reg.processBody(args,
 				dependant.BodyParams,
 				body,
+				options,
 			)...,
 		)
 	}
 
 	if dependant.ServiceParams != nil {
-		issues = append(
-			issues,
-			processService(
-				dig,
-				logger,
-				dependant.ServiceParams,
-			)...,
+		serviceIssues, err := processService(args,
+			services,
+			state,
+			dependant.ServiceParams,
 		)
+		if err != nil {
+			return nil, nil, err
+		}
+		issues = append(issues, serviceIssues...)
 	}
 
 	if dependant.ContextParams != nil {
 		issues = append(
 			issues,
-			processContext(
+			processContext(args,
 				dependant.ContextParams,
 				req,
 			)...,
 		)
 	}
 
+	for _, field := range dependant.ClaimsParams {
+		if issue := parseClaims(args, field, req); issue != nil {
+			issues = append(issues, issue)
+		}
+	}
+
+	if len(dependant.Providers) > 0 {
+		providerIssues, err := processProviders(args, dependant.Providers, state.solved, func(d *providerModel) (arguments, []*issue, error) {
+			return reg.solveDependant(webscoketManeger, services, state, w, d.depend, body, nil)
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		issues = append(issues, providerIssues...)
+	}
+
+	if dependant.Stream != nil {
+		issues = append(issues, parseStream(args, dependant.Stream, req)...)
+	}
+
+	for _, field := range dependant.Injected {
+		args[field.I] = injectedValue(field.Type, state, w, webscoketManeger)
+	}
+
 	if dependant.Request != nil {
-		if err := safeSetField(dependant.Request, req); err != nil {
+		if err := safeSetField(args, dependant.Request, req); err != nil {
 			issues = append(
 				issues,
 				newIssue(
@@ -810,8 +1048,10 @@ func solveDependant(
 				),
 			)
 		}
-	} else if dependant.Response != nil {
-		if err := safeSetField(dependant.Response, w.W); err != nil {
+	}
+
+	if dependant.Response != nil {
+		if err := safeSetField(args, dependant.Response, w.Writer()); err != nil {
 			issues = append(
 				issues,
 				newIssue(
@@ -821,8 +1061,10 @@ func solveDependant(
 				),
 			)
 		}
-	} else if dependant.Websocket != nil {
-		if err := safeSetField(dependant.Websocket, webscoketManeger); err != nil {
+	}
+
+	if dependant.Websocket != nil && webscoketManeger != nil {
+		if err := safeSetField(args, dependant.Websocket, webscoketManeger); err != nil {
 			issues = append(
 				issues,
 				newIssue(
@@ -834,10 +1076,10 @@ func solveDependant(
 		}
 	}
 
-	return issues
+	return args, issues, nil
 }
 
-func processDependant(dependant *dependant) []reflect.Value {
+func processDependant(dependant *dependant, args arguments) []reflect.Value {
 	var allModels []*model
 
 	sections := [][]*model{
@@ -849,16 +1091,27 @@ func processDependant(dependant *dependant) []reflect.Value {
 		dependant.FormParams,
 		dependant.ServiceParams,
 		dependant.ContextParams,
+		dependant.ClaimsParams,
+		dependant.Injected,
 	}
 
 	for _, section := range sections {
 		allModels = append(allModels, section...)
 	}
 
+	for _, sm := range dependant.StructParams {
+		allModels = append(allModels, sm.model)
+	}
+
+	for _, pm := range dependant.Providers {
+		allModels = append(allModels, pm.model)
+	}
+
 	individualModels := []*model{
 		dependant.Request,
 		dependant.Response,
 		dependant.Websocket,
+		dependant.Stream,
 	}
 
 	for _, model := range individualModels {
@@ -873,11 +1126,9 @@ func processDependant(dependant *dependant) []reflect.Value {
 
 	result := make([]reflect.Value, 0, len(allModels))
 	for _, model := range allModels {
-		var value reflect.Value
+		value := args[model.I]
 
-		if model.Default != nil {
-			value = reflect.ValueOf(model.Default)
-		} else {
+		if !value.IsValid() {
 			value = reflect.Zero(model.ReflectType)
 		}
 
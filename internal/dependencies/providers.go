@@ -0,0 +1,175 @@
+package dependencies
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// provider builds the value of Dependency[T] parameters from the request.
+// Its own parameters use the same wrappers as handlers, including
+// Dependency[U] for other providers.
+type provider struct {
+	fn         reflect.Value
+	typ        reflect.Type
+	returnsErr bool
+}
+
+type providerModel struct {
+	*model
+	provider *provider
+	depend   *dependant
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// RegisterProvider makes fn the source of every Dependency[T] parameter,
+// where T is the first result of fn, in place of the DI container. fn must
+// return T or (T, error) and is called at most once per request. Providers
+// must be registered before the application builds its handler.
+func (reg *Registry) RegisterProvider(fn interface{}) {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func {
+		panic("RegisterProvider requires a function")
+	}
+
+	t := v.Type()
+	switch {
+	case t.NumOut() == 1:
+	case t.NumOut() == 2 && t.Out(1) == errorType:
+	default:
+		panic(fmt.Sprintf("provider %s must return T or (T, error)", funcName(fn)))
+	}
+
+	reg.providers.Store(t.Out(0), &provider{
+		fn:         v,
+		typ:        t.Out(0),
+		returnsErr: t.NumOut() == 2,
+	})
+}
+
+func (reg *Registry) lookupProvider(t reflect.Type) (*provider, bool) {
+	p, ok := reg.providers.Load(t)
+	if !ok {
+		return nil, false
+	}
+	return p.(*provider), true
+}
+
+// DependencyError wraps the error a provider returned while a request was
+// being resolved.
+type DependencyError struct {
+	Provider string
+	Err      error
+}
+
+func (e *DependencyError) Error() string {
+	return fmt.Sprintf("provider %s: %v", e.Provider, e.Err)
+}
+
+func (e *DependencyError) Unwrap() error {
+	return e.Err
+}
+
+func (reg *Registry) newProviderModel(m *model, p *provider, chain []reflect.Type) (*providerModel, *ParamError) {
+	depend, err := reg.providerDependant(p, chain)
+	if err != nil {
+		return nil, newParamError(m.Name, m.ReflectType, err.Error())
+	}
+	return &providerModel{model: m, provider: p, depend: depend}, nil
+}
+
+// providerDependant analyzes the parameters of p. chain holds the types of
+// the providers being analyzed above it, so that a provider reaching
+// itself again is reported as a cycle instead of recursing forever.
+func (reg *Registry) providerDependant(p *provider, chain []reflect.Type) (*dependant, error) {
+	if i := slices.Index(chain, p.typ); i >= 0 {
+		cycle := make([]string, 0, len(chain)-i+1)
+		for _, t := range append(chain[i:], p.typ) {
+			cycle = append(cycle, t.String())
+		}
+		return nil, fmt.Errorf("dependency cycle: %s", strings.Join(cycle, " -> "))
+	}
+
+	fn := p.fn.Interface()
+	key := generateCacheKey(fn)
+
+	if cached, exists := reg.dependCache.Load(key); exists {
+		return cached.(*dependant), nil
+	}
+
+	params, err := reg.analyzeFunctionWithCache(fn)
+	if err != nil {
+		return nil, fmt.Errorf("provider %s: %w", funcName(fn), err)
+	}
+
+	depend, problems := reg.getDependant(params, append(slices.Clip(chain), p.typ))
+	if depend.Stream != nil {
+		problems = append(problems, newParamError(depend.Stream.Name, depend.Stream.ReflectType, "providers cannot stream the request body"))
+	}
+	if depend.Websocket != nil {
+		problems = append(problems, newParamError(depend.Websocket.Name, depend.Websocket.ReflectType, "providers cannot take the websocket manager"))
+	}
+	for _, field := range depend.Injected {
+		if field.Type == wsManagerType {
+			problems = append(problems, newParamError(field.Name, field.ReflectType, "providers cannot take the websocket manager"))
+		}
+	}
+	if len(problems) > 0 {
+		reasons := make([]string, len(problems))
+		for i, problem := range problems {
+			reasons[i] = problem.Error()
+		}
+		return nil, fmt.Errorf("provider %s: %s", funcName(fn), strings.Join(reasons, "; "))
+	}
+
+	reg.dependCache.Store(key, depend)
+	return depend, nil
+}
+
+// processProviders calls the provider of each model, reusing values
+// already built for this request in solved. A provider whose own inputs
+// fail to bind is not called, and its issues are reported once.
+func processProviders(
+	args arguments,
+	models []*providerModel,
+	solved map[reflect.Type]reflect.Value,
+	solve func(*providerModel) (arguments, []*issue, error),
+) ([]*issue, error) {
+	var issues []*issue
+
+	for _, pm := range models {
+		value, cached := solved[pm.provider.typ]
+		if !cached {
+			providerArgs, providerIssues, err := solve(pm)
+			if err != nil {
+				return nil, err
+			}
+
+			if len(providerIssues) == 0 {
+				results := pm.provider.fn.Call(processDependant(pm.depend, providerArgs))
+				if pm.provider.returnsErr && !results[1].IsNil() {
+					return nil, &DependencyError{
+						Provider: funcName(pm.provider.fn.Interface()),
+						Err:      results[1].Interface().(error),
+					}
+				}
+				value = results[0]
+			}
+
+			solved[pm.provider.typ] = value
+			issues = append(issues, providerIssues...)
+		}
+
+		if !value.IsValid() {
+			continue
+		}
+
+		instance := reflect.New(pm.ReflectType).Elem()
+		instance.Field(0).Set(value)
+		args[pm.I] = instance
+	}
+
+	return issues, nil
+}
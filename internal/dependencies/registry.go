@@ -0,0 +1,83 @@
+package dependencies
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/AbrahamBass/swiftapi/internal/types"
+)
+
+var paramRegistry sync.Map
+
+// Registry holds what one application adds to binding: converters, time
+// layouts, body decoders, providers and whether parameter names may be
+// read from source, along with the analysis of its handlers, which
+// depends on them. Applications sharing a process each have their own.
+type Registry struct {
+	converters   sync.Map
+	decoders     sync.Map
+	providers    sync.Map
+	sourceLookup atomic.Bool
+
+	layoutsMu   sync.RWMutex
+	timeLayouts []string
+
+	paramCache  sync.Map
+	dependCache sync.Map
+}
+
+func NewRegistry() *Registry {
+	reg := &Registry{
+		timeLayouts: []string{time.RFC3339Nano, time.DateTime, time.DateOnly},
+	}
+	reg.sourceLookup.Store(true)
+
+	reg.RegisterDecoder(string(types.ApplicationJSON), json.Unmarshal)
+	reg.RegisterDecoder(string(types.ApplicationXML), xml.Unmarshal)
+	reg.RegisterDecoder("text/xml", xml.Unmarshal)
+	reg.RegisterDecoder(string(types.ApplicationMsgPack), decodeMsgPack)
+	reg.RegisterDecoder("application/x-msgpack", decodeMsgPack)
+	reg.RegisterDecoder(string(types.TextPlain), decodePlain)
+	return reg
+}
+
+// SetSourceLookup controls whether parameter names missing from
+// RegisterParams may be read from the handler's Go source file at
+// startup.
+func (reg *Registry) SetSourceLookup(enabled bool) {
+	reg.sourceLookup.Store(enabled)
+}
+
+// registryKey identifies a function by its runtime name. Method values
+// carry a "-fm" suffix that the matching method expression lacks, so both
+// forms share one entry.
+func registryKey(fn interface{}) string {
+	name := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
+	return strings.TrimSuffix(name, "-fm")
+}
+
+// RegisterParams records the parameter names of fn so they never have to
+// be read from source. Methods may be registered through their method
+// expression, e.g. (*Handler).Get, listing names without the receiver.
+// Names describe the function itself, so they are shared by every
+// application.
+func RegisterParams(fn interface{}, names ...string) {
+	if reflect.ValueOf(fn).Kind() != reflect.Func {
+		panic("RegisterParams requires a function")
+	}
+	paramRegistry.Store(registryKey(fn), names)
+}
+
+func registeredParams(fn interface{}) ([]string, bool) {
+	names, ok := paramRegistry.Load(registryKey(fn))
+	if !ok {
+		return nil, false
+	}
+	return names.([]string), true
+}
@@ -1,14 +1,19 @@
 package dependencies
 
 import (
+	"context"
+	"errors"
+	"mime/multipart"
 	"net/http"
 	"reflect"
 
 	c "github.com/AbrahamBass/swiftapi/internal/context"
+	"github.com/AbrahamBass/swiftapi/internal/messages"
 	"github.com/AbrahamBass/swiftapi/internal/responses"
 	"github.com/AbrahamBass/swiftapi/internal/types"
 	"github.com/AbrahamBass/swiftapi/internal/ws"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 )
@@ -17,96 +22,283 @@ type issue struct {
 	Loc  []string        `json:"loc"`
 	Msg  string          `json:"msg"`
 	Type types.IssueType `json:"type"`
+
+	format string
+	args   []any
 }
 
-func newIssue(location []string, message string, errorType types.IssueType) *issue {
+// newIssue builds an issue whose message is format rendered with args.
+// Msg holds the English text; the format is kept so that the response can
+// be localized for the client.
+func newIssue(location []string, format string, errorType types.IssueType, args ...any) *issue {
 	return &issue{
-		Loc:  location,
-		Msg:  message,
-		Type: errorType,
+		Loc:    location,
+		Msg:    messages.Format(format, args...),
+		Type:   errorType,
+		format: format,
+		args:   args,
 	}
 }
 
+// issuesProblem answers binding issues as a problem document, with their
+// messages in lang.
+func issuesProblem(r *http.Request, issues []*issue, catalogs *messages.Catalogs, lang string) responses.ProblemDetails {
+	status := issuesStatus(issues)
+
+	var detail string
+	switch status {
+	case http.StatusUnauthorized:
+		detail = "The request is not authenticated."
+	case http.StatusRequestEntityTooLarge:
+		detail = "The request body is too large."
+	case http.StatusUnsupportedMediaType:
+		detail = "The request body's media type is not supported."
+	default:
+		detail = "The request did not pass validation."
+	}
+
+	errs := make([]responses.ProblemError, len(issues))
+	for i, issue := range issues {
+		errs[i] = responses.ProblemError{
+			Loc:  issue.Loc,
+			Msg:  catalogs.Localize(lang, issue.format, issue.args...),
+			Type: issue.Type,
+		}
+	}
+
+	return responses.ProblemDetails{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+		Errors:   errs,
+	}
+}
+
+// issuesStatus picks the response status for binding issues: 401 when
+// claims were asked of a request without a usable principal, 413 for an
+// oversized body, 415 when the body could not be decoded for its media
+// type, 422 otherwise.
+func issuesStatus(issues []*issue) int {
+	for _, issue := range issues {
+		switch issue.Type {
+		case types.Unauthenticated, types.InvalidClaims:
+			return http.StatusUnauthorized
+		case types.BodyTooLarge:
+			return http.StatusRequestEntityTooLarge
+		case types.UnsupportedMediaType:
+			return http.StatusUnsupportedMediaType
+		}
+	}
+	return http.StatusUnprocessableEntity
+}
+
 type dependencyResolver struct {
 	webscoketManeger *ws.WebsocketManager
-	dig              types.IDigContainer
+	services         *Services
 	logger           *zap.Logger
 	handler          interface{}
 	req              *http.Request
 	w                *responses.ResponseWriter
 	body             interface{}
+	binding          *routeBinding
+	state            *requestState
 }
 
-func newDependencyResolver(dig types.IDigContainer, logger *zap.Logger, handler interface{}, req *http.Request, w *responses.ResponseWriter, webscoketManeger *ws.WebsocketManager) *dependencyResolver {
+func newDependencyResolver(services *Services, logger *zap.Logger, handler interface{}, scope types.IRequestScope, w *responses.ResponseWriter, webscoketManeger *ws.WebsocketManager, binding *routeBinding) *dependencyResolver {
 	return &dependencyResolver{
-		dig:              dig,
+		services:         services,
 		logger:           logger,
 		handler:          handler,
-		req:              req,
+		req:              scope.Request(),
 		w:                w,
 		webscoketManeger: webscoketManeger,
+		binding:          binding,
+		state:            newRequestState(scope),
 	}
 }
 
-func (dr *dependencyResolver) resolve() ([]reflect.Value, []*issue) {
-	body, issues := extractBody(dr.req)
-	if len(issues) > 0 {
-		return nil, issues
+func (dr *dependencyResolver) resolve() ([]reflect.Value, []*issue, error) {
+	depend, err := dr.binding.registry.analyzeDependenciesWithCache(dr.handler)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// A declared length over the limit is refused before anything reads
+	// the body, FileStream handlers included.
+	if dr.binding.bodyLimit > 0 && dr.req.ContentLength > dr.binding.bodyLimit {
+		return nil, []*issue{bodyTooLarge([]string{"body"}, dr.binding.bodyLimit)}, nil
+	}
+
+	// A FileStream reads the body itself, so it must not be consumed here,
+	// and handlers binding nothing from the body leave it unread.
+	if depend.Stream == nil && depend.needsBody() {
+		body, issues := extractBody(dr.req, dr.binding)
+		if len(issues) > 0 {
+			return nil, issues, nil
+		}
+		dr.body = body
 	}
-	dr.body = body
 
-	depend, err := analyzeDependenciesWithCache(dr.handler)
+	args, issues, err := dr.binding.registry.solveDependant(dr.webscoketManeger, dr.services, dr.state, dr.w, depend, dr.body, dr.binding.options)
 	if err != nil {
-		dr.logger.Fatal("🚨 Failed to analyze handler dependencies",
-			zap.Error(err),
-		)
+		return nil, nil, err
+	}
+	if len(issues) > 0 {
+		return nil, issues, nil
 	}
 
-	if issues := solveDependant(dr.webscoketManeger, dr.dig, dr.logger, dr.req, dr.w, depend, dr.body); len(issues) > 0 {
-		return nil, issues
+	return processDependant(depend, args), nil, nil
+}
+
+// cleanup runs the cleanups of the scoped and transient services built
+// for the request and removes the temporary files a parsed multipart body
+// left on disk, once the handler is done with them.
+func (dr *dependencyResolver) cleanup() {
+	dr.state.close()
+
+	if form, ok := dr.body.(*multipart.Form); ok {
+		_ = form.RemoveAll()
 	}
+}
 
-	return processDependant(depend), nil
+// limitBody caps the request body so that neither middlewares nor the
+// binder can read past the configured limit.
+func limitBody(w http.ResponseWriter, r *http.Request, limit int64) {
+	if limit > 0 && r.Body != nil && r.Body != http.NoBody {
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+	}
 }
 
-func (dr *dependencyResolver) invoke(handler interface{}, values []reflect.Value) interface{} {
+// invoke calls handler and splits what it returned into the response and
+// the error, which is either the second result or the only one when the
+// handler returns just an error. A nil pointer or interface response is
+// answered like no response at all, with 204.
+func (dr *dependencyResolver) invoke(handler interface{}, values []reflect.Value) (interface{}, error) {
 	handlerValue := reflect.ValueOf(handler)
 	results := handlerValue.Call(values)
 
 	if len(results) == 0 {
-		return nil
+		return nil, nil
+	}
+
+	last := results[len(results)-1]
+	if last.Type() == errorType {
+		if !last.IsNil() {
+			return nil, last.Interface().(error)
+		}
+		if len(results) == 1 {
+			return nil, nil
+		}
+	}
+
+	result := results[0]
+	if (result.Kind() == reflect.Pointer || result.Kind() == reflect.Interface) && result.IsNil() {
+		return nil, nil
+	}
+	return result.Interface(), nil
+}
+
+// sendError answers err as a problem response. Errors no mapper expected
+// are logged with the request ID the client is given, so that the two can
+// be matched.
+func sendError(rw *responses.ResponseWriter, r *http.Request, logger *zap.Logger, mapping *responses.ErrorMapping, err error) {
+	var depErr *DependencyError
+	if errors.As(err, &depErr) {
+		err = depErr.Err
+	}
+
+	requestID := c.RequestID(r)
+	if requestID == "" {
+		requestID = uuid.NewString()
+	}
+
+	result, expected := mapping.Problem(err, r, requestID)
+	if !expected {
+		logger.Error("🚨 Unhandled handler error",
+			zap.String("request_id", requestID),
+			zap.String("path", r.URL.Path),
+			zap.Error(err),
+		)
 	}
+	rw.Send(result)
+}
+
+// sendInternalError answers 500 for a failure that has already been
+// logged.
+func sendInternalError(rw *responses.ResponseWriter, r *http.Request, mapping *responses.ErrorMapping) {
+	rw.Send(mapping.Respond(r, responses.ProblemDetails{
+		Type:      "about:blank",
+		Title:     http.StatusText(http.StatusInternalServerError),
+		Status:    http.StatusInternalServerError,
+		Detail:    "An unexpected error occurred.",
+		Instance:  r.URL.Path,
+		RequestID: c.RequestID(r),
+	}))
+}
 
-	return results[0].Interface()
+// sendIssues answers binding issues in the language the client asked for.
+func sendIssues(rw *responses.ResponseWriter, r *http.Request, mapping *responses.ErrorMapping, issues []*issue) {
+	catalogs := mapping.Messages()
+	problem := issuesProblem(r, issues, catalogs, catalogs.Negotiate(r.Header.Get("Accept-Language")))
+	problem.RequestID = c.RequestID(r)
+	rw.Send(mapping.Respond(r, problem))
 }
 
 func HTTPWrapper(
-	dig types.IDigContainer,
+	services *Services,
 	logger *zap.Logger,
 	handler interface{},
+	config RouteConfig,
 	middlewares ...types.Middleware,
 ) http.HandlerFunc {
+	binding := config.binding()
+
 	return func(w http.ResponseWriter, r *http.Request) {
+		limitBody(w, r, binding.bodyLimit)
+		if binding.timeout > 0 {
+			timeoutCtx, cancel := context.WithTimeout(r.Context(), binding.timeout)
+			defer cancel()
+			r = r.WithContext(timeoutCtx)
+		}
+
 		rw := responses.NewResponseWriter(w)
-		ctx := c.NewContext(rw, r)
+		ctx := c.NewContext(rw, r, binding.errors)
 
 		fn := func(currentScope types.IRequestScope) {
 			resolver := newDependencyResolver(
-				dig,
+				services,
 				logger,
 				handler,
-				currentScope.Request(),
+				currentScope,
 				rw,
 				nil,
+				binding,
 			)
-			deps, issues := resolver.resolve()
+			defer resolver.cleanup()
+
+			deps, issues, err := resolver.resolve()
+			var depErr *DependencyError
+			if errors.As(err, &depErr) {
+				sendError(rw, currentScope.Request(), logger, binding.errors, err)
+				return
+			}
+			if err != nil {
+				logger.Error("🚨 Failed to analyze handler dependencies", zap.Error(err))
+				sendInternalError(rw, currentScope.Request(), binding.errors)
+				return
+			}
 			if issues != nil {
-				rw.SetStatusCode(http.StatusUnprocessableEntity)
-				rw.Send(issues)
+				sendIssues(rw, currentScope.Request(), binding.errors, issues)
+				return
+			}
+			response, err := resolver.invoke(handler, deps)
+			if err != nil {
+				sendError(rw, currentScope.Request(), logger, binding.errors, err)
 				return
 			}
-			response := resolver.invoke(handler, deps)
-			rw.Send(response)
+			rw.Send(binding.errors.Complete(currentScope.Request(), c.RequestID(currentScope.Request()), response))
 		}
 
 		chain := buildMiddlewareChain(
@@ -119,44 +311,83 @@ func HTTPWrapper(
 	}
 }
 
+func NativeWrapper(
+	handler http.Handler,
+	errors *responses.ErrorMapping,
+	middlewares ...types.Middleware,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rw := responses.NewResponseWriter(w)
+		ctx := c.NewContext(rw, r, errors)
+
+		fn := func(currentScope types.IRequestScope) {
+			handler.ServeHTTP(currentScope.Response(), currentScope.Request())
+		}
+
+		chain := buildMiddlewareChain(
+			fn,
+			middlewares,
+		)
+
+		chain(ctx)
+	}
+}
+
 func WebSocketWrapper(
-	dig types.IDigContainer,
+	services *Services,
 	logger *zap.Logger,
 	handler interface{},
 	wsUpgrader *websocket.Upgrader,
+	config RouteConfig,
 	middlewares ...types.Middleware,
 ) http.HandlerFunc {
+	binding := config.binding()
+
 	return func(w http.ResponseWriter, r *http.Request) {
+		limitBody(w, r, binding.bodyLimit)
 		rw := responses.NewResponseWriter(w)
-		ctx := c.NewContext(rw, r)
+		ctx := c.NewContext(rw, r, binding.errors)
 
 		fn := func(currentScope types.IRequestScope) {
 
 			wsManager := ws.NewWebsocketManager(wsUpgrader, logger)
 
 			resolver := newDependencyResolver(
-				dig,
+				services,
 				logger,
 				handler,
-				currentScope.Request(),
+				currentScope,
 				rw,
 				wsManager,
+				binding,
 			)
+			defer resolver.cleanup()
 
-			deps, issues := resolver.resolve()
+			deps, issues, err := resolver.resolve()
+			var depErr *DependencyError
+			if errors.As(err, &depErr) {
+				sendError(rw, currentScope.Request(), logger, binding.errors, err)
+				return
+			}
+			if err != nil {
+				logger.Error("🚨 Failed to analyze handler dependencies", zap.Error(err))
+				sendInternalError(rw, currentScope.Request(), binding.errors)
+				return
+			}
 			if issues != nil {
-				rw.SetStatusCode(http.StatusUnprocessableEntity)
-				rw.Send(issues)
+				sendIssues(rw, currentScope.Request(), binding.errors, issues)
 				return
 			}
 
-			err := wsManager.Connect(w, r)
+			err = wsManager.Connect(w, r)
 			if err != nil {
 				logger.Error("Error connecting WebSocket", zap.Error(err))
 				return
 			}
 
-			_ = resolver.invoke(handler, deps)
+			if _, err := resolver.invoke(handler, deps); err != nil {
+				logger.Error("🚨 Websocket handler failed", zap.Error(err))
+			}
 			wsManager.Start()
 		}
 
@@ -0,0 +1,257 @@
+package dependencies
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/AbrahamBass/swiftapi/internal/types"
+
+	"go.uber.org/dig"
+)
+
+// lifetime is a constructor whose instances live no longer than a request.
+// Scoped instances are built once per request, transient ones every time
+// they are asked for.
+type lifetime struct {
+	fn         reflect.Value
+	scoped     bool
+	cleanup    bool
+	returnsErr bool
+}
+
+// Services is the application container. Singletons are kept by dig;
+// scoped and transient constructors are kept here and run per request,
+// with their cleanups deferred until the response has been written.
+type Services struct {
+	types.IDigContainer
+	lifetimes sync.Map
+
+	mu         sync.Mutex
+	provisions []provision
+	dryRun     *dig.Container
+}
+
+// provision is a Provide call, kept so that it can be replayed into a
+// dry-run container when handlers are analyzed.
+type provision struct {
+	constructor interface{}
+	opts        []dig.ProvideOption
+}
+
+func NewServices(dig types.IDigContainer) *Services {
+	return &Services{IDigContainer: dig}
+}
+
+var (
+	cleanupType  = reflect.TypeOf(func() {})
+	requestType  = reflect.TypeOf(&http.Request{})
+	reqScopeType = reflect.TypeOf((*types.IRequestScope)(nil)).Elem()
+)
+
+func (s *Services) Provide(constructor interface{}, opts ...dig.ProvideOption) error {
+	if err := s.IDigContainer.Provide(constructor, opts...); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.provisions = append(s.provisions, provision{constructor, opts})
+	s.dryRun = nil
+	return nil
+}
+
+// buildable reports why dig could not build t, without running any
+// constructor.
+func (s *Services) buildable(t reflect.Type) error {
+	s.mu.Lock()
+	if s.dryRun == nil {
+		s.dryRun = dig.New(dig.DryRun(true))
+		for _, p := range s.provisions {
+			_ = s.dryRun.Provide(p.constructor, p.opts...)
+		}
+	}
+	container := s.dryRun
+	s.mu.Unlock()
+
+	if _, err := resolveDependency(container, t); err != nil {
+		return fmt.Errorf("cannot build %s: %v", t, dig.RootCause(err))
+	}
+	return nil
+}
+
+// Scoped registers constructor to build its result once per request.
+// Besides other services it may take the *http.Request and the
+// IRequestScope being served, and may return a cleanup func after the
+// value and before an optional error.
+func (s *Services) Scoped(constructor interface{}) error {
+	return s.register(constructor, true)
+}
+
+// Transient registers constructor to build a new value every time one is
+// needed. It accepts the same signatures as Scoped.
+func (s *Services) Transient(constructor interface{}) error {
+	return s.register(constructor, false)
+}
+
+func (s *Services) register(constructor interface{}, scoped bool) error {
+	v := reflect.ValueOf(constructor)
+	if v.Kind() != reflect.Func {
+		return fmt.Errorf("constructor must be a function, got %T", constructor)
+	}
+
+	t := v.Type()
+	lt := &lifetime{fn: v, scoped: scoped}
+
+	outs := make([]reflect.Type, t.NumOut())
+	for i := range outs {
+		outs[i] = t.Out(i)
+	}
+	switch {
+	case len(outs) == 1:
+	case len(outs) == 2 && outs[1] == errorType:
+		lt.returnsErr = true
+	case len(outs) == 2 && outs[1] == cleanupType:
+		lt.cleanup = true
+	case len(outs) == 3 && outs[1] == cleanupType && outs[2] == errorType:
+		lt.cleanup, lt.returnsErr = true, true
+	default:
+		return fmt.Errorf("constructor %s must return T, (T, error), (T, func()) or (T, func(), error)", funcName(constructor))
+	}
+
+	s.lifetimes.Store(outs[0], lt)
+	return nil
+}
+
+func (s *Services) lifetime(t reflect.Type) (*lifetime, bool) {
+	lt, ok := s.lifetimes.Load(t)
+	if !ok {
+		return nil, false
+	}
+	return lt.(*lifetime), true
+}
+
+// check reports a service that cannot be built: one no constructor
+// provides, or a scoped or transient constructor that needs its own
+// result, directly or through other constructors.
+func (s *Services) check(t reflect.Type, chain []reflect.Type) error {
+	if t == requestType || t == reqScopeType {
+		if len(chain) == 0 {
+			return fmt.Errorf("%s is not a service", t)
+		}
+		return nil
+	}
+
+	lt, ok := s.lifetime(t)
+	if !ok {
+		return s.buildable(t)
+	}
+
+	if i := slices.Index(chain, t); i >= 0 {
+		cycle := make([]string, 0, len(chain)-i+1)
+		for _, c := range append(chain[i:], t) {
+			cycle = append(cycle, c.String())
+		}
+		return fmt.Errorf("service cycle: %s", strings.Join(cycle, " -> "))
+	}
+
+	chain = append(slices.Clip(chain), t)
+	fnType := lt.fn.Type()
+	for i := 0; i < fnType.NumIn(); i++ {
+		if err := s.check(fnType.In(i), chain); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolve returns the service of type t for the request state describes,
+// building scoped and transient services and asking dig for the rest.
+func (s *Services) resolve(t reflect.Type, state *requestState) (reflect.Value, error) {
+	lt, ok := s.lifetime(t)
+	if !ok {
+		instance, err := resolveDependency(s.IDigContainer, t)
+		if err != nil {
+			return reflect.Value{}, &DependencyError{Provider: t.String(), Err: err}
+		}
+
+		value := reflect.New(t).Elem()
+		if instance != nil {
+			value.Set(reflect.ValueOf(instance))
+		}
+		return value, nil
+	}
+
+	if lt.scoped {
+		if value, built := state.scoped[t]; built {
+			return value, nil
+		}
+	}
+
+	fnType := lt.fn.Type()
+	in := make([]reflect.Value, fnType.NumIn())
+	for i := range in {
+		switch paramType := fnType.In(i); paramType {
+		case requestType:
+			in[i] = reflect.ValueOf(state.req)
+		case reqScopeType:
+			in[i] = reflect.ValueOf(&state.scope).Elem()
+		default:
+			value, err := s.resolve(paramType, state)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			in[i] = value
+		}
+	}
+
+	out := lt.fn.Call(in)
+	if lt.returnsErr {
+		if err := out[len(out)-1]; !err.IsNil() {
+			return reflect.Value{}, &DependencyError{
+				Provider: funcName(lt.fn.Interface()),
+				Err:      err.Interface().(error),
+			}
+		}
+	}
+	if lt.cleanup && !out[1].IsNil() {
+		state.cleanups = append(state.cleanups, out[1].Interface().(func()))
+	}
+
+	if lt.scoped {
+		state.scoped[t] = out[0]
+	}
+	return out[0], nil
+}
+
+// requestState holds what is built while one request is resolved: the
+// values of providers and scoped services, and the cleanups to run once
+// the response has been written.
+type requestState struct {
+	req      *http.Request
+	scope    types.IRequestScope
+	solved   map[reflect.Type]reflect.Value
+	scoped   map[reflect.Type]reflect.Value
+	cleanups []func()
+}
+
+func newRequestState(scope types.IRequestScope) *requestState {
+	return &requestState{
+		req:    scope.Request(),
+		scope:  scope,
+		solved: make(map[reflect.Type]reflect.Value),
+		scoped: make(map[reflect.Type]reflect.Value),
+	}
+}
+
+// close runs the cleanups in the reverse order of construction, so that a
+// service is torn down before the services it was built from.
+func (st *requestState) close() {
+	for i := len(st.cleanups) - 1; i >= 0; i-- {
+		st.cleanups[i]()
+	}
+	st.cleanups = nil
+}
@@ -41,7 +41,11 @@ func formatHeaderKey(key string) string {
 	return result
 }
 
-func convertToType(value interface{}, targetType reflect.Type) (interface{}, error) {
+func (reg *Registry) convertToType(value interface{}, targetType reflect.Type) (interface{}, error) {
+	if conv, ok := reg.customConverter(targetType); ok {
+		return conv(cast.ToString(value))
+	}
+
 	switch targetType.Kind() {
 	case reflect.Int:
 		return cast.ToIntE(value)
@@ -72,7 +76,26 @@ func convertToType(value interface{}, targetType reflect.Type) (interface{}, err
 	case reflect.Bool:
 		return cast.ToBoolE(value)
 	default:
-		return nil, fmt.Errorf("Tipo no soportado: %s", targetType.Kind())
+		return nil, fmt.Errorf("unsupported type: %s", targetType.Kind())
+	}
+}
+
+func (reg *Registry) convertible(targetType reflect.Type) bool {
+	if reg.isMultiValue(targetType) {
+		targetType = targetType.Elem()
+	}
+
+	if _, ok := reg.customConverter(targetType); ok {
+		return true
+	}
+
+	switch targetType.Kind() {
+	case reflect.Int, reflect.Int64, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float64, reflect.Float32, reflect.String, reflect.Bool:
+		return true
+	default:
+		return false
 	}
 }
 
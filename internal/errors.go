@@ -0,0 +1,39 @@
+package swiftapi
+
+import (
+	"fmt"
+	"strings"
+)
+
+type RouteError struct {
+	Route string
+	Err   error
+}
+
+func (e *RouteError) Error() string {
+	return e.Route + ": " + e.Err.Error()
+}
+
+func (e *RouteError) Unwrap() error {
+	return e.Err
+}
+
+// CompileError gathers every problem found while building the mux so
+// they can be fixed in one pass instead of one restart at a time.
+type CompileError struct {
+	Errors []error
+}
+
+func (e *CompileError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d problem(s) found while compiling routes:", len(e.Errors))
+	for _, err := range e.Errors {
+		b.WriteString("\n")
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+func (e *CompileError) Unwrap() []error {
+	return e.Errors
+}
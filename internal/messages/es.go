@@ -0,0 +1,73 @@
+package messages
+
+var spanish = Catalog{
+	// Problem titles
+	"Bad Request":              "Solicitud incorrecta",
+	"Unauthorized":             "No autorizado",
+	"Forbidden":                "Prohibido",
+	"Not Found":                "No encontrado",
+	"Method Not Allowed":       "Método no permitido",
+	"Conflict":                 "Conflicto",
+	"Request Entity Too Large": "Cuerpo de la solicitud demasiado grande",
+	"Unsupported Media Type":   "Tipo de contenido no soportado",
+	"Unprocessable Entity":     "Entidad no procesable",
+	"Too Many Requests":        "Demasiadas solicitudes",
+	"Internal Server Error":    "Error interno del servidor",
+	"Service Unavailable":      "Servicio no disponible",
+	"Gateway Timeout":          "Tiempo de espera agotado",
+
+	// Problem details
+	"The request did not pass validation.":            "La solicitud no superó la validación.",
+	"The request body is too large.":                  "El cuerpo de la solicitud es demasiado grande.",
+	"The request body's media type is not supported.": "El tipo de contenido del cuerpo de la solicitud no está soportado.",
+	"The request is not authenticated.":               "La solicitud no está autenticada.",
+	"An unexpected error occurred.":                   "Ocurrió un error inesperado.",
+
+	// Binding
+	"field required":                             "campo obligatorio",
+	"file required":                              "archivo obligatorio",
+	"form data is required":                      "se requieren datos de formulario",
+	"request body is required":                   "se requiere el cuerpo de la solicitud",
+	"request body exceeds the limit of %d bytes": "el cuerpo de la solicitud supera el límite de %d bytes",
+	"missing required context parameter: %s":     "falta el parámetro de contexto obligatorio: %s",
+	"Conversion error: %v":                       "error de conversión: %v",
+	"Failed to parse form data: %s":              "no se pudieron leer los datos del formulario: %s",
+	"Failed to parse multipart form: %s":         "no se pudo leer el formulario multipart: %s",
+	"Failed to read request body":                "no se pudo leer el cuerpo de la solicitud",
+	"Invalid %s body: %v":                        "cuerpo %s no válido: %v",
+	"Invalid JSON syntax at position %d: %v":     "sintaxis JSON no válida en la posición %d: %v",
+	"Invalid unmarshal target type":              "tipo de destino no válido",
+	"JSON decoding error: %v":                    "error al decodificar JSON: %v",
+	"Type mismatch at %s (expected %s, got %s)":  "tipo incorrecto en %s (se esperaba %s, se recibió %s)",
+	"Unsupported form type: %T":                  "tipo de formulario no soportado: %T",
+	"Unsupported media type %q":                  "tipo de contenido no soportado %q",
+	"a %s body is required: %v":                  "se requiere un cuerpo %s: %v",
+	"form bodies cannot be decoded into %s":      "los formularios no se pueden decodificar en %s",
+	"invalid body type: %T":                      "tipo de cuerpo no válido: %T",
+
+	// Validation
+	"must be a valid email address": "debe ser una dirección de correo válida",
+	"must be a valid absolute URL":  "debe ser una URL absoluta válida",
+	"must be a valid UUID":          "debe ser un UUID válido",
+	"must be one of: %s":            "debe ser uno de: %s",
+	"must match the pattern %s":     "debe coincidir con el patrón %s",
+	"must be exactly %s":            "debe ser exactamente %s",
+	"must be at least %s":           "debe ser al menos %s",
+	"must be at most %s":            "debe ser como máximo %s",
+	"length must be exactly %s":     "la longitud debe ser exactamente %s",
+	"length must be at least %s":    "la longitud debe ser al menos %s",
+	"length must be at most %s":     "la longitud debe ser como máximo %s",
+
+	// Authentication
+	"Invalid authorization format": "Formato de autorización no válido",
+	"Token invalid":                "Token no válido",
+	"Token expired":                "Token expirado",
+	"Expiration claim required":    "Se requiere el claim de expiración",
+	"Issuer not allowed":           "Emisor no permitido",
+	"Audience not allowed":         "Audiencia no permitida",
+	"A required scope is missing.": "Falta un scope requerido.",
+	"A required role is missing.":  "Falta un rol requerido.",
+	"A required claim is missing.": "Falta un claim requerido.",
+	"authentication required":      "se requiere autenticación",
+	"claims do not match %s: %v":   "los claims no encajan en %s: %v",
+}
@@ -0,0 +1,130 @@
+package messages
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Catalog translates message formats into one language. Keys are the
+// English formats used in the source, verbs included, so a message missing
+// from a catalog falls back to English.
+type Catalog map[string]string
+
+// DefaultLanguage is the language of the message formats themselves.
+const DefaultLanguage = "en"
+
+// builtin are the translations every application starts with.
+var builtin = map[string]Catalog{"es": spanish}
+
+// Catalogs holds the translations of one application, by language. A nil
+// Catalogs knows only the built-in translations.
+type Catalogs struct {
+	mu       sync.RWMutex
+	catalogs map[string]Catalog
+}
+
+func NewCatalogs() *Catalogs {
+	catalogs := make(map[string]Catalog, len(builtin))
+	for lang, catalog := range builtin {
+		catalogs[lang] = catalog
+	}
+	return &Catalogs{catalogs: catalogs}
+}
+
+// Register adds the translations in catalog to lang, replacing any that
+// were already set for the same formats.
+func (c *Catalogs) Register(lang string, catalog Catalog) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	lang = strings.ToLower(lang)
+	merged := Catalog{}
+	for k, v := range c.catalogs[lang] {
+		merged[k] = v
+	}
+	for k, v := range catalog {
+		merged[k] = v
+	}
+	c.catalogs[lang] = merged
+}
+
+// Localize renders format in lang. Formats without arguments are returned
+// as they are, so that literal text containing "%" survives.
+func (c *Catalogs) Localize(lang, format string, args ...any) string {
+	if translated, ok := c.catalog(lang)[format]; ok {
+		format = translated
+	}
+	return Format(format, args...)
+}
+
+// Format renders format in the default language.
+func Format(format string, args ...any) string {
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+func (c *Catalogs) catalog(lang string) Catalog {
+	if c == nil {
+		return builtin[lang]
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.catalogs[lang]
+}
+
+// Negotiate picks the language to answer in from an Accept-Language
+// header, preferring higher quality values and falling back from a
+// regional tag such as es-MX to its base language.
+func (c *Catalogs) Negotiate(acceptLanguage string) string {
+	type candidate struct {
+		tag string
+		q   float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		candidates = append(candidates, candidate{strings.ToLower(tag), q})
+	}
+
+	slices.SortStableFunc(candidates, func(a, b candidate) int {
+		switch {
+		case a.q > b.q:
+			return -1
+		case a.q < b.q:
+			return 1
+		}
+		return 0
+	})
+
+	for _, candidate := range candidates {
+		if candidate.q <= 0 {
+			continue
+		}
+		base, _, _ := strings.Cut(candidate.tag, "-")
+		for _, tag := range []string{candidate.tag, base} {
+			if tag == DefaultLanguage {
+				return DefaultLanguage
+			}
+			if c.catalog(tag) != nil {
+				return tag
+			}
+		}
+	}
+	return DefaultLanguage
+}
@@ -0,0 +1,118 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/AbrahamBass/swiftapi/internal/auth"
+	c "github.com/AbrahamBass/swiftapi/internal/context"
+	"github.com/AbrahamBass/swiftapi/internal/responses"
+	"github.com/AbrahamBass/swiftapi/internal/types"
+
+	"go.uber.org/zap"
+)
+
+// PolicyFunc adapts a function to IPolicy.
+type PolicyFunc func(scope types.IRequestScope, principal *auth.Principal) error
+
+func (f PolicyFunc) Authorize(scope types.IRequestScope, principal *auth.Principal) error {
+	return f(scope, principal)
+}
+
+var (
+	errScope = &responses.HTTPError{Status: http.StatusForbidden, Detail: "A required scope is missing."}
+	errRole  = &responses.HTTPError{Status: http.StatusForbidden, Detail: "A required role is missing."}
+	errClaim = &responses.HTTPError{Status: http.StatusForbidden, Detail: "A required claim is missing."}
+)
+
+// HasScope authorizes principals granted every one of scopes, through the
+// "scope" or "scp" claims.
+func HasScope(scopes ...string) types.IPolicy {
+	return PolicyFunc(func(_ types.IRequestScope, principal *auth.Principal) error {
+		for _, scope := range scopes {
+			if !principal.HasScope(scope) {
+				return errScope
+			}
+		}
+		return nil
+	})
+}
+
+// HasRole authorizes principals holding any of roles, through the "roles"
+// or "role" claims.
+func HasRole(roles ...string) types.IPolicy {
+	return PolicyFunc(func(_ types.IRequestScope, principal *auth.Principal) error {
+		if slices.ContainsFunc(roles, principal.HasRole) {
+			return nil
+		}
+		return errRole
+	})
+}
+
+// HasClaim authorizes principals whose claim at path, as understood by
+// Principal.Lookup, holds any of values. Without values the claim only
+// has to be present. A claim may hold a single value, a list or a space
+// separated string.
+func HasClaim(path string, values ...string) types.IPolicy {
+	return PolicyFunc(func(_ types.IRequestScope, principal *auth.Principal) error {
+		claim, ok := principal.Lookup(path)
+		if !ok {
+			return errClaim
+		}
+		if len(values) == 0 {
+			return nil
+		}
+
+		held := auth.List(claim)
+		if held == nil {
+			held = []string{fmt.Sprint(claim)}
+		}
+		for _, value := range values {
+			if slices.Contains(held, value) {
+				return nil
+			}
+		}
+		return errClaim
+	})
+}
+
+// AuthorizationMiddleware answers 401 to requests without a principal and
+// 403, as a problem, to those a policy denies. Policies run in order and
+// the first denial wins. Only the detail of an HTTPError reaches the
+// client; any other error a policy returns is logged with the request ID
+// and answered with a plain "Forbidden".
+func AuthorizationMiddleware(policies []types.IPolicy, logger *zap.Logger) types.Middleware {
+	return func(scope types.IRequestScope, handler func()) {
+		principal, ok := scope.Principal()
+		if !ok {
+			scope.Throw(http.StatusUnauthorized, "The request is not authenticated.")
+			return
+		}
+
+		for _, policy := range policies {
+			if err := policy.Authorize(scope, principal); err != nil {
+				status, detail := http.StatusForbidden, http.StatusText(http.StatusForbidden)
+				var httpErr *responses.HTTPError
+				if errors.As(err, &httpErr) {
+					status = httpErr.Status
+					detail = http.StatusText(status)
+					if httpErr.Detail != "" {
+						detail = httpErr.Detail
+					}
+				} else {
+					logger.Error("🚨 Authorization policy failed",
+						zap.String("request_id", c.RequestID(scope.Request())),
+						zap.String("path", scope.Request().URL.Path),
+						zap.Error(err),
+					)
+				}
+				scope.Throw(status, detail)
+				return
+			}
+		}
+
+		handler()
+	}
+}
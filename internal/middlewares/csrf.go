@@ -108,7 +108,7 @@ func CsrfMiddleware(config types.ICsrfConfig) types.Middleware {
 
 	parts := strings.SplitN(config.TokenLookup(), ":", 2)
 	if len(parts) != 2 {
-		panic("Formato de TokenLookup inválido. Usa 'cookie:nombre', 'header:nombre', o 'form:nombre'")
+		panic("invalid TokenLookup format, use 'cookie:name', 'header:name' or 'form:name'")
 	}
 
 	sourceType := parts[0]
@@ -122,7 +122,7 @@ func CsrfMiddleware(config types.ICsrfConfig) types.Middleware {
 	case "form":
 		opts = append(opts, csrf.FieldName(sourceName))
 	default:
-		panic("Fuente de token CSRF no soportada: " + sourceType)
+		panic("unsupported CSRF token source: " + sourceType)
 	}
 
 	csrfMiddleware := csrf.Protect(
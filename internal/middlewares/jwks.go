@@ -0,0 +1,314 @@
+package middlewares
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultJWKSRefresh is how long a fetched key set is trusted when no
+// refresh interval is given.
+const DefaultJWKSRefresh = time.Hour
+
+// jwksMinRefresh bounds how often a token naming an unknown kid may cause
+// the key set to be fetched again, so that forged kids cannot flood the
+// identity provider.
+const jwksMinRefresh = 30 * time.Second
+
+// JWKS is a JSON Web Key Set read from a file or URL. Keys are cached for
+// the refresh interval and fetched again once it expires, or earlier when
+// a token names a kid the cached set lacks, so that rotated keys are
+// picked up. If a fetch fails the keys already known keep being used.
+type JWKS struct {
+	fetch   func(ctx context.Context) ([]byte, error)
+	refresh time.Duration
+
+	mu      sync.Mutex
+	keys    map[string]interface{}
+	fetched time.Time
+	tried   time.Time
+	pending *jwksFetch
+}
+
+// errKeySetUnavailable marks lookups that failed because the key set could
+// not be fetched, rather than because of the token.
+var errKeySetUnavailable = errors.New("key set unavailable")
+
+// jwksFetch is a fetch in progress, shared by every caller that needs the
+// key set while it runs.
+type jwksFetch struct {
+	done chan struct{}
+	err  error
+}
+
+func newJWKS(refresh time.Duration, fetch func(ctx context.Context) ([]byte, error)) *JWKS {
+	if refresh <= 0 {
+		refresh = DefaultJWKSRefresh
+	}
+	return &JWKS{fetch: fetch, refresh: refresh}
+}
+
+// NewJWKSFile reads the key set in the file at path.
+func NewJWKSFile(path string, refresh time.Duration) *JWKS {
+	return newJWKS(refresh, func(context.Context) ([]byte, error) {
+		return os.ReadFile(path)
+	})
+}
+
+// NewJWKSURL fetches the key set served at url with client, or with a
+// client timing out after ten seconds when client is nil.
+func NewJWKSURL(url string, refresh time.Duration, client *http.Client) *JWKS {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return newJWKS(refresh, func(ctx context.Context) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+
+		res, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("fetching %s: %s", url, res.Status)
+		}
+		return io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	})
+}
+
+// Key returns the key called kid. Keys without a kid in the set are
+// returned for tokens without one. A known key is returned at once, even
+// while the set is being refreshed; an unknown kid waits for the refresh,
+// or until ctx is done. Failing to fetch the set, when no key is known
+// for kid, is reported as errKeySetUnavailable.
+func (s *JWKS) Key(ctx context.Context, kid string) (interface{}, error) {
+	s.mu.Lock()
+	now := time.Now()
+	key, known := s.keys[kid]
+	stale := now.Sub(s.fetched) >= s.refresh
+	if (stale || !known) && now.Sub(s.tried) >= jwksMinRefresh {
+		s.start(ctx)
+	}
+	pending := s.pending
+	loaded := !s.fetched.IsZero()
+	s.mu.Unlock()
+
+	if known {
+		return key, nil
+	}
+	if pending == nil {
+		if !loaded {
+			return nil, errKeySetUnavailable
+		}
+		return nil, fmt.Errorf("no key with kid %q in the key set", kid)
+	}
+
+	if err := pending.wait(ctx); err != nil {
+		return nil, fmt.Errorf("%w: %w", errKeySetUnavailable, err)
+	}
+
+	s.mu.Lock()
+	key, known = s.keys[kid]
+	s.mu.Unlock()
+
+	if !known {
+		return nil, fmt.Errorf("no key with kid %q in the key set", kid)
+	}
+	return key, nil
+}
+
+// Refresh fetches the key set now, or waits for the fetch already
+// running.
+func (s *JWKS) Refresh(ctx context.Context) error {
+	s.mu.Lock()
+	pending := s.start(ctx)
+	s.mu.Unlock()
+
+	return pending.wait(ctx)
+}
+
+// start begins a fetch unless one is running, and returns it. The fetch
+// keeps the values of ctx but not its cancellation, since callers other
+// than the one starting it may be waiting for the result. s.mu must be
+// held.
+func (s *JWKS) start(ctx context.Context) *jwksFetch {
+	if s.pending != nil {
+		return s.pending
+	}
+
+	pending := &jwksFetch{done: make(chan struct{})}
+	s.pending = pending
+	s.tried = time.Now()
+
+	go s.load(context.WithoutCancel(ctx), pending)
+	return pending
+}
+
+func (s *JWKS) load(ctx context.Context, pending *jwksFetch) {
+	data, err := s.fetch(ctx)
+	var keys map[string]interface{}
+	if err == nil {
+		keys, err = parseJWKS(data)
+	}
+
+	s.mu.Lock()
+	if err == nil {
+		s.keys = keys
+		s.fetched = time.Now()
+	}
+	s.pending = nil
+	pending.err = err
+	s.mu.Unlock()
+
+	close(pending.done)
+}
+
+func (f *jwksFetch) wait(ctx context.Context) error {
+	select {
+	case <-f.done:
+		return f.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWKS decodes the signing keys of a key set. Encryption keys and
+// key types other than RSA, EC and OKP are skipped.
+func parseJWKS(data []byte) (map[string]interface{}, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid key set: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key, err := k.publicKey()
+		if errors.Is(err, errUnsupportedKey) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+var errUnsupportedKey = errors.New("unsupported key type")
+
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("RSA exponent too large")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, errUnsupportedKey
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// ParsePublicKeyPEM reads an RSA, ECDSA or Ed25519 public key, or the key
+// of a certificate, from PEM.
+func ParsePublicKeyPEM(data []byte) (interface{}, error) {
+	if key, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		return key, nil
+	}
+	if key, err := jwt.ParseECPublicKeyFromPEM(data); err == nil {
+		return key, nil
+	}
+	if key, err := jwt.ParseEdPublicKeyFromPEM(data); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("no RSA, ECDSA or Ed25519 public key found in PEM data")
+}
@@ -0,0 +1,272 @@
+package middlewares
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func rsaKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func ecKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func publicPEM(t *testing.T, key crypto.PublicKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key interface{}) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, jwt.MapClaims{
+		"sub": "tester",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func verify(ctx context.Context, config *JWTConfig, signed string) error {
+	_, err := jwt.Parse(signed, func(token *jwt.Token) (interface{}, error) {
+		return verificationKey(ctx, config, token)
+	})
+	return err
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) jwk {
+	return jwk{Kty: "RSA", Kid: kid, Use: "sig", N: b64(key.N.Bytes()), E: b64(big.NewInt(int64(key.E)).Bytes())}
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) jwk {
+	return jwk{Kty: "EC", Kid: kid, Crv: "P-256", X: b64(key.X.FillBytes(make([]byte, 32))), Y: b64(key.Y.FillBytes(make([]byte, 32)))}
+}
+
+// keySetServer serves the keys it holds as a JWKS document, counting the
+// fetches. While gate is set, fetches block until it is closed.
+type keySetServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	keys    []jwk
+	gate    chan struct{}
+	fetches atomic.Int64
+}
+
+func newKeySetServer(t *testing.T, keys ...jwk) *keySetServer {
+	s := &keySetServer{keys: keys}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.fetches.Add(1)
+
+		s.mu.Lock()
+		gate, keys := s.gate, s.keys
+		s.mu.Unlock()
+
+		if gate != nil {
+			<-gate
+		}
+		_ = json.NewEncoder(w).Encode(map[string][]jwk{"keys": keys})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *keySetServer) set(gate chan struct{}, keys ...jwk) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gate, s.keys = gate, keys
+}
+
+func TestVerifyWithPEMPublicKey(t *testing.T) {
+	rsaPriv, ecPriv := rsaKey(t), ecKey(t)
+
+	config := NewJWTConfig()
+	config.SetAlgorithms([]string{"RS256", "ES256"})
+	for kid, pub := range map[string]crypto.PublicKey{"": &rsaPriv.PublicKey, "ec": &ecPriv.PublicKey} {
+		key, err := ParsePublicKeyPEM([]byte(publicPEM(t, pub)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		config.AddPublicKey(kid, key)
+	}
+
+	ctx := context.Background()
+	if err := verify(ctx, config, sign(t, jwt.SigningMethodRS256, "", rsaPriv)); err != nil {
+		t.Errorf("RS256 token without kid: %v", err)
+	}
+	if err := verify(ctx, config, sign(t, jwt.SigningMethodES256, "ec", ecPriv)); err != nil {
+		t.Errorf("ES256 token with kid ec: %v", err)
+	}
+	if err := verify(ctx, config, sign(t, jwt.SigningMethodRS256, "", rsaKey(t))); err == nil {
+		t.Error("token signed by another RSA key was accepted")
+	}
+
+	if _, err := ParsePublicKeyPEM([]byte("not a key")); err == nil {
+		t.Error("ParsePublicKeyPEM accepted data without a key")
+	}
+}
+
+func TestVerifySelectsKeyByKid(t *testing.T) {
+	first, second := rsaKey(t), ecKey(t)
+	server := newKeySetServer(t, rsaJWK("first", &first.PublicKey), ecJWK("second", &second.PublicKey))
+
+	config := NewJWTConfig()
+	config.SetAlgorithms([]string{"RS256", "ES256"})
+	config.SetKeySet(NewJWKSURL(server.URL, time.Hour, nil))
+
+	ctx := context.Background()
+	if err := verify(ctx, config, sign(t, jwt.SigningMethodRS256, "first", first)); err != nil {
+		t.Errorf("token with kid first: %v", err)
+	}
+	if err := verify(ctx, config, sign(t, jwt.SigningMethodES256, "second", second)); err != nil {
+		t.Errorf("token with kid second: %v", err)
+	}
+	if err := verify(ctx, config, sign(t, jwt.SigningMethodRS256, "second", first)); err == nil {
+		t.Error("RSA token naming the EC key was accepted")
+	}
+	if got := server.fetches.Load(); got != 1 {
+		t.Errorf("key set fetched %d times, want 1", got)
+	}
+}
+
+func TestVerifyRejectsWrongAlgorithmOrKeyType(t *testing.T) {
+	priv := rsaKey(t)
+	pemKey := publicPEM(t, &priv.PublicKey)
+
+	key, err := ParsePublicKeyPEM([]byte(pemKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := NewJWTConfig()
+	config.SetAlgorithms([]string{"RS256", "HS256"})
+	config.AddPublicKey("rsa", key)
+
+	ctx := context.Background()
+	// The public key must not be accepted as an HMAC secret.
+	if err := verify(ctx, config, sign(t, jwt.SigningMethodHS256, "rsa", []byte(pemKey))); err == nil {
+		t.Error("HS256 token signed with the public key as secret was accepted")
+	}
+	if err := verify(ctx, config, sign(t, jwt.SigningMethodES256, "rsa", ecKey(t))); err == nil {
+		t.Error("ES256 token naming an RSA key was accepted")
+	}
+
+	config.SetAlgorithms([]string{"ES256"})
+	if err := verify(ctx, config, sign(t, jwt.SigningMethodRS256, "rsa", priv)); err == nil {
+		t.Error("RS256 token was accepted while only ES256 is allowed")
+	}
+}
+
+func TestJWKSRefreshesOnUnknownKid(t *testing.T) {
+	old, rotated := rsaKey(t), rsaKey(t)
+	server := newKeySetServer(t, rsaJWK("old", &old.PublicKey))
+
+	keySet := NewJWKSURL(server.URL, time.Hour, nil)
+	ctx := context.Background()
+	if _, err := keySet.Key(ctx, "old"); err != nil {
+		t.Fatal(err)
+	}
+
+	gate := make(chan struct{})
+	server.set(gate, rsaJWK("old", &old.PublicKey), rsaJWK("new", &rotated.PublicKey))
+	keySet.mu.Lock()
+	keySet.tried = time.Time{}
+	keySet.mu.Unlock()
+
+	// Every caller asking for the new kid shares one fetch.
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := keySet.Key(ctx, "new")
+			errs <- err
+		}()
+	}
+
+	// Known keys are served without waiting for the fetch.
+	deadline, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if _, err := keySet.Key(deadline, "old"); err != nil {
+		t.Errorf("cached key during refresh: %v", err)
+	}
+
+	close(gate)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("rotated key: %v", err)
+		}
+	}
+	if got := server.fetches.Load(); got != 2 {
+		t.Errorf("key set fetched %d times, want 2", got)
+	}
+
+	// Further unknown kids are throttled rather than fetched again.
+	if _, err := keySet.Key(ctx, "forged"); err == nil {
+		t.Error("unknown kid was accepted")
+	}
+	if got := server.fetches.Load(); got != 2 {
+		t.Errorf("key set fetched %d times after a throttled kid, want 2", got)
+	}
+}
+
+func TestJWKSKeyHonorsContext(t *testing.T) {
+	server := newKeySetServer(t)
+	gate := make(chan struct{})
+	defer close(gate)
+	server.set(gate)
+
+	keySet := NewJWKSURL(server.URL, time.Hour, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := keySet.Key(ctx, "any"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Key while the fetch hangs: %v, want %v", err, context.DeadlineExceeded)
+	}
+}
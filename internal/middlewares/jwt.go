@@ -1,14 +1,19 @@
 package middlewares
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/AbrahamBass/swiftapi/internal/auth"
+	c "github.com/AbrahamBass/swiftapi/internal/context"
 	"github.com/AbrahamBass/swiftapi/internal/types"
 
 	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
 )
 
 type JWTConfig struct {
@@ -16,6 +21,8 @@ type JWTConfig struct {
 	algorithms []string
 	audience   []string
 	issuer     []string
+	publicKeys map[string]interface{}
+	keySet     types.IKeySet
 }
 
 func NewJWTConfig() *JWTConfig {
@@ -24,6 +31,7 @@ func NewJWTConfig() *JWTConfig {
 		algorithms: []string{"HS256"},
 		audience:   []string{},
 		issuer:     []string{},
+		publicKeys: map[string]interface{}{},
 	}
 }
 
@@ -58,7 +66,54 @@ func (j *JWTConfig) SetIssuer(issuer []string) {
 	j.issuer = issuer
 }
 
-func JWTMiddleware(jwtConfig types.IJWTConfig) types.Middleware {
+func (j *JWTConfig) PublicKeys() map[string]interface{} {
+	return j.publicKeys
+}
+
+// AddPublicKey verifies tokens naming kid with key. A key added with an
+// empty kid verifies tokens that do not name one.
+func (j *JWTConfig) AddPublicKey(kid string, key interface{}) {
+	j.publicKeys[kid] = key
+}
+
+func (j *JWTConfig) KeySet() types.IKeySet {
+	return j.keySet
+}
+
+func (j *JWTConfig) SetKeySet(keySet types.IKeySet) {
+	j.keySet = keySet
+}
+
+// verificationKey picks the key for token: a public key added for its
+// kid, then one from the key set, and for HMAC algorithms the shared
+// secret. The key type has to match the algorithm, so a public key can
+// never be used as an HMAC secret.
+func verificationKey(ctx context.Context, config types.IJWTConfig, token *jwt.Token) (interface{}, error) {
+	alg := token.Method.Alg()
+	if !contains(config.Algorithms(), alg) {
+		return nil, fmt.Errorf("signing algorithm not allowed: %v", token.Header["alg"])
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	if key, ok := config.PublicKeys()[kid]; ok {
+		return key, nil
+	}
+
+	if _, hmac := token.Method.(*jwt.SigningMethodHMAC); hmac && len(config.Key()) > 0 {
+		return config.Key(), nil
+	}
+
+	if keySet := config.KeySet(); keySet != nil {
+		return keySet.Key(ctx, kid)
+	}
+	return nil, fmt.Errorf("no key for kid %q", kid)
+}
+
+// JWTMiddleware authenticates requests by their bearer token. Why a token
+// was rejected is logged rather than shown to the client, and a key set
+// that cannot be fetched is answered with 503, since the token may well
+// be valid.
+func JWTMiddleware(jwtConfig types.IJWTConfig, logger *zap.Logger) types.Middleware {
 	return func(scope types.IRequestScope, handler func()) {
 		authHeader, ok := scope.MetaVal("Authorization")
 		if authHeader == "" || !ok {
@@ -74,14 +129,24 @@ func JWTMiddleware(jwtConfig types.IJWTConfig) types.Middleware {
 		tokenString := parts[1]
 
 		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if !contains(jwtConfig.Algorithms(), token.Method.Alg()) {
-				return nil, fmt.Errorf("algoritmo de firma no permitido: %v", token.Header["alg"])
-			}
-			return jwtConfig.Key(), nil
+			return verificationKey(scope.Request().Context(), jwtConfig, token)
 		})
 
+		if errors.Is(err, errKeySetUnavailable) {
+			logger.Error("🚨 Failed to fetch the JWT key set",
+				zap.String("request_id", c.RequestID(scope.Request())),
+				zap.Error(err),
+			)
+			scope.Throw(http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable))
+			return
+		}
 		if err != nil {
-			scope.Throw(401, "Invalid token: "+err.Error())
+			logger.Warn("Rejected bearer token",
+				zap.String("request_id", c.RequestID(scope.Request())),
+				zap.String("path", scope.Request().URL.Path),
+				zap.Error(err),
+			)
+			scope.Throw(http.StatusUnauthorized, "Token invalid")
 			return
 		}
 
@@ -104,7 +169,7 @@ func JWTMiddleware(jwtConfig types.IJWTConfig) types.Middleware {
 			if len(jwtConfig.Issuer()) > 0 {
 				iss, ok := claims["iss"].(string)
 				if !ok || !contains(jwtConfig.Issuer(), iss) {
-					scope.Throw(http.StatusUnauthorized, "Emisor (issuer) no permitido")
+					scope.Throw(http.StatusUnauthorized, "Issuer not allowed")
 					return
 				}
 			}
@@ -112,7 +177,7 @@ func JWTMiddleware(jwtConfig types.IJWTConfig) types.Middleware {
 			if len(jwtConfig.Audience()) > 0 {
 				aud, ok := claims["aud"].(string)
 				if !ok || !contains(jwtConfig.Audience(), aud) {
-					scope.Throw(http.StatusUnauthorized, "Audiencia (audience) no permitida")
+					scope.Throw(http.StatusUnauthorized, "Audience not allowed")
 					return
 				}
 			}
@@ -120,6 +185,9 @@ func JWTMiddleware(jwtConfig types.IJWTConfig) types.Middleware {
 		}
 
 		scope.SetBaggage("claims", token.Claims)
+		if claims, ok := token.Claims.(jwt.MapClaims); ok {
+			scope.SetPrincipal(auth.NewPrincipal("Bearer", claims))
+		}
 
 		handler()
 	}
@@ -0,0 +1,72 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	c "github.com/AbrahamBass/swiftapi/internal/context"
+	"github.com/AbrahamBass/swiftapi/internal/responses"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+func authenticate(config *JWTConfig, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/secure", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+
+	scope := c.NewContext(responses.NewResponseWriter(rec), req, nil)
+	JWTMiddleware(config, zap.NewNop())(scope, func() {})
+	return rec
+}
+
+// TestJWTMiddlewareHidesVerificationErrors checks that neither a rejected
+// token nor an unreachable key set reveals why verification failed, and
+// that the latter is not blamed on the client.
+func TestJWTMiddlewareHidesVerificationErrors(t *testing.T) {
+	priv := rsaKey(t)
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream down", http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	server := newKeySetServer(t, rsaJWK("current", &priv.PublicKey))
+
+	tests := []struct {
+		name   string
+		keySet string
+		token  string
+		status int
+		detail string
+	}{
+		{"key set unavailable", down.URL, sign(t, jwt.SigningMethodRS256, "current", priv), http.StatusServiceUnavailable, "Service Unavailable"},
+		{"unknown kid", server.URL, sign(t, jwt.SigningMethodRS256, "forged", priv), http.StatusUnauthorized, "Token invalid"},
+		{"malformed token", server.URL, "not.a.token", http.StatusUnauthorized, "Token invalid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := NewJWTConfig()
+			config.SetAlgorithms([]string{"RS256"})
+			config.SetKeySet(NewJWKSURL(tt.keySet, time.Hour, nil))
+
+			rec := authenticate(config, tt.token)
+			if rec.Code != tt.status {
+				t.Errorf("status %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			body := rec.Body.String()
+			if !strings.Contains(body, `"detail":"`+tt.detail+`"`) {
+				t.Errorf("body %s, want detail %q", body, tt.detail)
+			}
+			for _, leak := range []string{tt.keySet, "kid", "fetching"} {
+				if strings.Contains(body, leak) {
+					t.Errorf("body %s reveals %q", body, leak)
+				}
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	c "github.com/AbrahamBass/swiftapi/internal/context"
 	"github.com/AbrahamBass/swiftapi/internal/responses"
 
 	"github.com/google/uuid"
@@ -17,6 +18,8 @@ func LoggingMiddleware(logger *zap.Logger) func(http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
 			requestID := uuid.New().String()
+			r = c.WithRequestID(r, requestID)
+			w.Header().Set("X-Request-ID", requestID)
 
 			cwr := responses.NewCustomResponseWriter(w)
 
@@ -6,9 +6,12 @@ import (
 	"github.com/AbrahamBass/swiftapi/internal/dependencies"
 
 	md "github.com/AbrahamBass/swiftapi/internal/middlewares"
+	"github.com/AbrahamBass/swiftapi/internal/responses"
 
 	"net/http"
 	"slices"
+	"strings"
+	"time"
 
 	"github.com/AbrahamBass/swiftapi/internal/types"
 
@@ -16,20 +19,30 @@ import (
 )
 
 type Mux struct {
-	dig               types.IDigContainer
+	services          *dependencies.Services
+	registry          *dependencies.Registry
 	logger            *zap.Logger
 	routers           []*APIRouter
-	static            map[string]string
+	static            map[string]http.Handler
 	globalMiddlewares []types.Middleware
 	jwtConfig         types.IJWTConfig
+	bodyLimit         int64
+	multipartMemory   int64
+	timeout           time.Duration
+	errors            *responses.ErrorMapping
+	tree              *node
 }
 
 func newMux() *Mux {
 	return &Mux{}
 }
 
-func (m *Mux) SetDig(dig types.IDigContainer) {
-	m.dig = dig
+func (m *Mux) SetServices(services *dependencies.Services) {
+	m.services = services
+}
+
+func (m *Mux) SetRegistry(registry *dependencies.Registry) {
+	m.registry = registry
 }
 
 func (m *Mux) SetRouters(routers []*APIRouter) {
@@ -40,7 +53,7 @@ func (m *Mux) SetLogger(Logger *zap.Logger) {
 	m.logger = Logger
 }
 
-func (m *Mux) SetStaticFile(static map[string]string) {
+func (m *Mux) SetStaticFile(static map[string]http.Handler) {
 	m.static = static
 }
 
@@ -52,65 +65,298 @@ func (m *Mux) SetJwtConfig(jwtConfig types.IJWTConfig) {
 	m.jwtConfig = jwtConfig
 }
 
-func (m *Mux) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+func (m *Mux) SetBodyLimit(limit int64) {
+	m.bodyLimit = limit
+}
 
-	for _, rgrp := range m.routers {
-		for _, rte := range rgrp.routes {
+func (m *Mux) SetMultipartMemory(memory int64) {
+	m.multipartMemory = memory
+}
 
-			match, params := rte.path.Match(req.URL.Path)
-			if !match {
-				continue
-			}
+func (m *Mux) SetTimeout(d time.Duration) {
+	m.timeout = d
+}
 
-			if !slices.Contains(rte.methods, req.Method) {
-				http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
-				return
-			}
+func (m *Mux) SetErrorMapping(mapping *responses.ErrorMapping) {
+	m.errors = mapping
+}
+
+// Compile resolves every route into its final pipeline. Middleware
+// chains, security and wrappers are fixed here, so serving a request
+// never writes to shared router state.
+func (m *Mux) Compile() error {
+	var errs []error
 
-			clonedReq := req.Clone(context.Background())
+	if _, err := namedRoutes(m.routers); err != nil {
+		errs = append(errs, err)
+	}
+
+	m.tree = newNode("")
+	for _, rgrp := range flattenRouters(m.routers) {
+		var security []types.Middleware
+		if rgrp.secured() && m.jwtConfig != nil {
+			security = append(security, md.JWTMiddleware(m.jwtConfig, m.logger))
+		}
 
-			if params != nil {
-				clonedReq = clonedReq.WithContext(
-					context.WithValue(clonedReq.Context(), 1, params),
-				)
+		for _, mnt := range rgrp.mounts {
+			err := m.mount(
+				mnt.prefix,
+				nil,
+				mnt.handler,
+				rootPath(mnt.handler),
+				combineMiddlewares(
+					m.globalMiddlewares,
+					rgrp.middlewareChain(),
+					security,
+					m.authorization(rgrp.policyChain()),
+				),
+			)
+			if err != nil {
+				errs = append(errs, &RouteError{Route: "mount " + mnt.prefix, Err: err})
 			}
+		}
 
-			if rgrp.authorization {
-				if m.jwtConfig != nil {
-					rgrp.middlewares = append(
-						rgrp.middlewares,
-						md.JWTMiddleware(m.jwtConfig),
-					)
-				}
+		for _, rte := range rgrp.routes {
+			err := rte.err
+			if err == nil {
+				err = m.registry.Analyze(rte.handler, rte.path.paramNames, rte.tags, m.services)
+			}
+			if err != nil {
+				errs = append(errs, &RouteError{
+					Route: strings.Join(rte.methods, ",") + " " + rte.path.original,
+					Err:   err,
+				})
+				continue
 			}
 
-			combinedMiddlewares := combineMiddlewares(
+			middlewares := combineMiddlewares(
 				m.globalMiddlewares,
-				rgrp.middlewares,
+				rgrp.middlewareChain(),
+				security,
 				rte.middlewares,
+				m.authorization(append(slices.Clip(rgrp.policyChain()), rte.policies...)),
 			)
 
-			if rte.isWebSocket {
-				dependencies.WebSocketWrapper(
-					m.dig,
-					m.logger,
-					rte.handler,
-					rte.wsUpgrader,
-					combinedMiddlewares...,
-				)(w, clonedReq)
-				return
+			err = m.tree.insert(rte.path.original, &muxEntry{
+				methods: rte.methods,
+				handler: m.wrap(rte, m.routeConfig(rgrp, rte), middlewares),
+			})
+			if err != nil {
+				errs = append(errs, &RouteError{
+					Route: strings.Join(rte.methods, ",") + " " + rte.path.original,
+					Err:   err,
+				})
+			}
+		}
+	}
+
+	for prefix, handler := range m.static {
+		if responder, ok := handler.(problemResponder); ok {
+			responder.SetErrorMapping(m.errors)
+		}
+		err := m.mount(
+			prefix,
+			[]string{http.MethodGet},
+			handler,
+			http.RedirectHandler(prefix+"/", http.StatusMovedPermanently),
+			m.globalMiddlewares,
+		)
+		if err != nil {
+			errs = append(errs, &RouteError{Route: "static " + prefix, Err: err})
+		}
+	}
+
+	if len(errs) > 0 {
+		return &CompileError{Errors: errs}
+	}
+	return nil
+}
+
+// problemResponder is implemented by handlers, such as the static file
+// server, that answer their own errors as problems of the application.
+type problemResponder interface {
+	SetErrorMapping(mapping *responses.ErrorMapping)
+}
+
+// authorization checks policies after every other middleware, so that
+// they see the principal set by the JWT middleware or by any middleware of
+// the route.
+func (m *Mux) authorization(policies []types.IPolicy) []types.Middleware {
+	if len(policies) == 0 {
+		return nil
+	}
+	return []types.Middleware{md.AuthorizationMiddleware(policies, m.logger)}
+}
+
+// mount serves handler for everything below prefix, with prefix stripped
+// from the request path, and bare for the prefix itself. Nil methods
+// accept any method.
+func (m *Mux) mount(
+	prefix string,
+	methods []string,
+	handler http.Handler,
+	bare http.Handler,
+	middlewares []types.Middleware,
+) error {
+	base := strings.TrimSuffix(prefix, "/")
+
+	err := m.tree.insert(base+"/{filepath...}", &muxEntry{
+		methods: methods,
+		handler: dependencies.NativeWrapper(
+			http.StripPrefix(base, handler),
+			m.errors,
+			middlewares...,
+		),
+	})
+	if err != nil || base == "" {
+		return err
+	}
+
+	return m.tree.insert(base, &muxEntry{
+		methods: methods,
+		handler: dependencies.NativeWrapper(bare, m.errors, middlewares...),
+	})
+}
+
+// rootPath serves handler with the request path replaced by "/", for
+// requests addressing a mount prefix without a trailing slash.
+func rootPath(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r2 := r.Clone(r.Context())
+		r2.URL.Path = "/"
+		r2.URL.RawPath = ""
+		handler.ServeHTTP(w, r2)
+	})
+}
 
+// routeConfig resolves the binding settings of a route. The closest
+// non-zero body limit wins: route, then routers, then application.
+func (m *Mux) routeConfig(rgrp *APIRouter, rte *APIRoute) dependencies.RouteConfig {
+	config := dependencies.RouteConfig{
+		Tags:            rte.tags,
+		BodyLimit:       rte.bodyLimit,
+		MultipartMemory: rte.memory,
+		Timeout:         rte.timeout,
+		Errors:          m.errors,
+		Registry:        m.registry,
+	}
+
+	if config.BodyLimit == 0 {
+		config.BodyLimit = rgrp.effectiveBodyLimit()
+	}
+	if config.BodyLimit == 0 {
+		config.BodyLimit = m.bodyLimit
+	}
+	if config.Timeout == 0 {
+		config.Timeout = rgrp.effectiveTimeout()
+	}
+	if config.Timeout == 0 {
+		config.Timeout = m.timeout
+	}
+	if config.MultipartMemory == 0 {
+		config.MultipartMemory = m.multipartMemory
+	}
+	return config
+}
+
+func (m *Mux) wrap(rte *APIRoute, config dependencies.RouteConfig, middlewares []types.Middleware) http.HandlerFunc {
+	if rte.isWebSocket {
+		return dependencies.WebSocketWrapper(
+			m.services,
+			m.logger,
+			rte.handler,
+			rte.wsUpgrader,
+			config,
+			middlewares...,
+		)
+	}
+
+	return dependencies.HTTPWrapper(
+		m.services,
+		m.logger,
+		rte.handler,
+		config,
+		middlewares...,
+	)
+}
+
+func (m *Mux) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var (
+		entry  *muxEntry
+		params map[string]string
+		leaves []*node
+	)
+
+	// A path may be matched by several nodes, e.g. {id} and {id:int}; the
+	// first one serving the method wins, and the rest only feed Allow.
+	m.tree.lookup(req.URL.Path, func(leaf *node, values []string) bool {
+		leaves = append(leaves, leaf)
+
+		entry = leaf.match(req.Method)
+		if entry == nil && req.Method == http.MethodHead {
+			if entry = leaf.match(http.MethodGet); entry != nil {
+				w = &headResponseWriter{ResponseWriter: w}
 			}
+		}
+		if entry == nil {
+			return false
+		}
+
+		params = entry.bind(values)
+		return true
+	})
 
-			dependencies.HTTPWrapper(
-				m.dig,
-				m.logger,
-				rte.handler,
-				combinedMiddlewares...,
-			)(w, clonedReq)
+	if len(leaves) == 0 {
+		m.sendStatus(w, req, http.StatusNotFound)
+		return
+	}
+
+	if entry == nil {
+		allow := strings.Join(allowed(leaves), ", ")
+
+		if req.Method == http.MethodOptions {
+			dependencies.NativeWrapper(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Allow", allow)
+					w.WriteHeader(http.StatusNoContent)
+				}),
+				m.errors,
+				m.globalMiddlewares...,
+			)(w, req)
 			return
 		}
+
+		w.Header().Set("Allow", allow)
+		m.sendStatus(w, req, http.StatusMethodNotAllowed)
+		return
 	}
 
-	http.NotFound(w, req)
+	clonedReq := req.Clone(req.Context())
+
+	if params != nil {
+		clonedReq = clonedReq.WithContext(
+			context.WithValue(clonedReq.Context(), 1, params),
+		)
+	}
+
+	entry.handler(w, clonedReq)
+}
+
+// sendStatus answers a request no route serves with the problem response
+// for status, rendered like every other problem of the application.
+func (m *Mux) sendStatus(w http.ResponseWriter, req *http.Request, status int) {
+	responses.NewResponseWriter(w).Send(m.errors.Respond(req, responses.ProblemDetails{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Instance: req.URL.Path,
+	}))
+}
+
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w *headResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
 }
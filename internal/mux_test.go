@@ -0,0 +1,276 @@
+package swiftapi_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AbrahamBass/swiftapi"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "mux-test-secret"
+
+func showItem(id swiftapi.Pathway[int]) string {
+	return fmt.Sprintf("item %d", id.Value)
+}
+
+func signedToken(t *testing.T) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "tester",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	signed, err := token.SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// TestServeHTTPConcurrentSecuredRouter serves a secured router from many
+// goroutines at once. Run with -race: the security and middleware chains
+// are built once by Compile and must not be touched per request.
+func TestServeHTTPConcurrentSecuredRouter(t *testing.T) {
+	var globalCalls, routerCalls atomic.Int64
+
+	app := swiftapi.Bootstrap()
+	app.JWTBearer().Key(testSecret).Apply()
+	app.AddMiddleware(func(scope swiftapi.RequestScope, next func()) {
+		globalCalls.Add(1)
+		next()
+	})
+	app.Include().Add(func(r swiftapi.APIRouter) {
+		r.Prefix("/secure")
+		r.Secure(true)
+		r.Wrap(func(scope swiftapi.RequestScope, next func()) {
+			routerCalls.Add(1)
+			next()
+		})
+		r.Handle(http.MethodGet, "/items/{id:int}", showItem)
+	}).Apply()
+
+	handler, err := app.Handler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := signedToken(t)
+
+	const workers, requests = 8, 50
+
+	for _, authorized := range []bool{true, false} {
+		t.Run(fmt.Sprintf("authorized=%t", authorized), func(t *testing.T) {
+			t.Parallel()
+
+			var wg sync.WaitGroup
+			for w := 0; w < workers; w++ {
+				wg.Add(1)
+				go func(w int) {
+					defer wg.Done()
+					for i := 0; i < requests; i++ {
+						id := w*requests + i
+						req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/secure/items/%d", id), nil)
+						if authorized {
+							req.Header.Set("Authorization", "Bearer "+token)
+						}
+
+						rec := httptest.NewRecorder()
+						handler.ServeHTTP(rec, req)
+
+						if !authorized {
+							if rec.Code != http.StatusUnauthorized {
+								t.Errorf("GET %s without a token: status %d, want 401", req.URL, rec.Code)
+							}
+							continue
+						}
+						if rec.Code != http.StatusOK {
+							t.Errorf("GET %s: status %d, want 200: %s", req.URL, rec.Code, rec.Body)
+							continue
+						}
+						if want := fmt.Sprintf("item %d", id); !strings.Contains(rec.Body.String(), want) {
+							t.Errorf("GET %s: body %q, want %q", req.URL, rec.Body, want)
+						}
+					}
+				}(w)
+			}
+			wg.Wait()
+		})
+	}
+
+	// Router middlewares run ahead of the token check, so both runs count.
+	t.Cleanup(func() {
+		const total = 2 * workers * requests
+		if got := globalCalls.Load(); got != total {
+			t.Errorf("global middleware ran %d times, want %d", got, total)
+		}
+		if got := routerCalls.Load(); got != total {
+			t.Errorf("router middleware ran %d times, want %d", got, total)
+		}
+	})
+}
+
+func newItemForm() string {
+	return "new item form"
+}
+
+func deleteItem(itemID swiftapi.Pathway[string]) string {
+	return "deleted " + itemID.Value
+}
+
+func TestServeHTTPRouting(t *testing.T) {
+	app := swiftapi.Bootstrap()
+	app.Include().Add(func(r swiftapi.APIRouter) {
+		r.Handle(http.MethodGet, "/items/{id}", showItem)
+		r.Handle(http.MethodDelete, "/items/{itemID}", deleteItem)
+		r.Handle(http.MethodGet, "/items/new", newItemForm)
+	}).Apply()
+
+	handler, err := app.Handler()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		allow  string
+		body   string
+	}{
+		{"param", http.MethodGet, "/items/7", http.StatusOK, "", "item 7"},
+		{"sibling param name", http.MethodDelete, "/items/7", http.StatusOK, "", "deleted 7"},
+		{"static before param", http.MethodGet, "/items/new", http.StatusOK, "", "new item form"},
+		{"head served by get", http.MethodHead, "/items/7", http.StatusOK, "", ""},
+		{"options", http.MethodOptions, "/items/7", http.StatusNoContent, "DELETE, GET, HEAD, OPTIONS", ""},
+		{"method not allowed", http.MethodPut, "/items/7", http.StatusMethodNotAllowed, "DELETE, GET, HEAD, OPTIONS", `"status":405`},
+		{"param behind static", http.MethodDelete, "/items/new", http.StatusOK, "", "deleted new"},
+		{"method not allowed on static and param", http.MethodPost, "/items/new", http.StatusMethodNotAllowed, "DELETE, GET, HEAD, OPTIONS", `"status":405`},
+		{"not found", http.MethodGet, "/items/7/parts", http.StatusNotFound, "", `"status":404`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if rec.Code != tt.status {
+				t.Errorf("%s %s: status %d, want %d: %s", tt.method, tt.path, rec.Code, tt.status, rec.Body)
+			}
+			if got := rec.Header().Get("Allow"); got != tt.allow {
+				t.Errorf("%s %s: Allow %q, want %q", tt.method, tt.path, got, tt.allow)
+			}
+			if tt.body == "" && rec.Body.Len() > 0 {
+				t.Errorf("%s %s: body %q, want none", tt.method, tt.path, rec.Body)
+			}
+			if !strings.Contains(rec.Body.String(), tt.body) {
+				t.Errorf("%s %s: body %q, want %q", tt.method, tt.path, rec.Body, tt.body)
+			}
+		})
+	}
+}
+
+func showUser(id swiftapi.Pathway[int]) string {
+	return fmt.Sprintf("user %d", id.Value)
+}
+
+func showUserByUUID(id swiftapi.Pathway[string]) string {
+	return "user uuid " + id.Value
+}
+
+func showUserByName(name swiftapi.Pathway[string]) string {
+	return "user named " + name.Value
+}
+
+func showCode(code swiftapi.Pathway[string]) string {
+	return "code " + code.Value
+}
+
+func serveFile(path swiftapi.Pathway[string]) string {
+	return "file " + path.Value
+}
+
+// TestServeHTTPSegmentConstraints checks that values breaking a
+// constraint fall through to the next route, or to 404, rather than
+// reaching the handler as a conversion issue.
+func TestServeHTTPSegmentConstraints(t *testing.T) {
+	app := swiftapi.Bootstrap()
+	app.Include().Add(func(r swiftapi.APIRouter) {
+		r.Handle(http.MethodGet, "/users/{id:int}", showUser)
+		r.Handle(http.MethodGet, "/users/{id:uuid}", showUserByUUID)
+		r.Handle(http.MethodGet, "/users/{name}", showUserByName)
+		r.Handle(http.MethodGet, "/codes/{code:[A-Z]+}", showCode)
+		r.Handle(http.MethodGet, "/files/{path...}", serveFile)
+	}).Apply()
+
+	handler, err := app.Handler()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const id = "6f1c2a4e-9b3d-4c5e-8f7a-0b1c2d3e4f5a"
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/users/42", http.StatusOK, "user 42"},
+		{"/users/" + id, http.StatusOK, "user uuid " + id},
+		{"/users/ada", http.StatusOK, "user named ada"},
+		{"/users/12ab", http.StatusOK, "user named 12ab"},
+		{"/codes/ABC", http.StatusOK, "code ABC"},
+		{"/codes/abc", http.StatusNotFound, `"status":404`},
+		{"/codes/AB1", http.StatusNotFound, `"status":404`},
+		{"/files/readme.md", http.StatusOK, "file readme.md"},
+		{"/files/docs/guide/intro.md", http.StatusOK, "file docs/guide/intro.md"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.status {
+				t.Errorf("GET %s: status %d, want %d: %s", tt.path, rec.Code, tt.status, rec.Body)
+			}
+			if !strings.Contains(rec.Body.String(), tt.body) {
+				t.Errorf("GET %s: body %q, want %q", tt.path, rec.Body, tt.body)
+			}
+		})
+	}
+}
+
+func TestHandlerReportsMalformedPatterns(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		want    string
+	}{
+		{"unclosed placeholder", "/users/{id", "unclosed placeholder"},
+		{"unknown type", "/users/{id:integer}", `unknown type "integer"`},
+		{"bad regex", "/users/{id:[0-9}", "invalid constraint"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := swiftapi.Bootstrap()
+			app.Include().Add(func(r swiftapi.APIRouter) {
+				r.Handle(http.MethodGet, tt.pattern, showUser)
+			}).Apply()
+
+			_, err := app.Handler()
+			if err == nil {
+				t.Fatalf("Handler() with %s compiled", tt.pattern)
+			}
+			for _, want := range []string{"GET " + tt.pattern, tt.want} {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Handler() error %q, want it to mention %q", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package responses
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/AbrahamBass/swiftapi/internal/messages"
+	"github.com/AbrahamBass/swiftapi/internal/types"
+)
+
+// ProblemDetails is a problem document as described by RFC 9457.
+type ProblemDetails struct {
+	Type      string         `json:"type,omitempty"`
+	Title     string         `json:"title"`
+	Status    int            `json:"status"`
+	Detail    string         `json:"detail,omitempty"`
+	Instance  string         `json:"instance,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
+	Errors    []ProblemError `json:"errors,omitempty"`
+}
+
+// ProblemError is one invalid input of a request, located by the path of
+// keys that leads to it.
+type ProblemError struct {
+	Loc  []string        `json:"loc"`
+	Msg  string          `json:"msg"`
+	Type types.IssueType `json:"type"`
+}
+
+// ProblemFormatter renders the response sent for a problem, for
+// applications that answer errors in a shape of their own.
+type ProblemFormatter func(r *http.Request, problem ProblemDetails) *IActionResult
+
+// HTTPError is an error answered with Status. Detail is shown to the
+// client, so it must not carry internal information.
+type HTTPError struct {
+	Status int
+	Detail string
+}
+
+func (e *HTTPError) Error() string {
+	if e.Detail != "" {
+		return e.Detail
+	}
+	return strings.ToLower(http.StatusText(e.Status))
+}
+
+// Is makes an HTTPError match the sentinel of its status, so that
+// errors.Is(&HTTPError{Status: 404, Detail: "no user 7"}, ErrNotFound)
+// holds.
+func (e *HTTPError) Is(target error) bool {
+	t, ok := target.(*HTTPError)
+	return ok && t.Detail == "" && t.Status == e.Status
+}
+
+var (
+	ErrBadRequest   = &HTTPError{Status: http.StatusBadRequest}
+	ErrUnauthorized = &HTTPError{Status: http.StatusUnauthorized}
+	ErrForbidden    = &HTTPError{Status: http.StatusForbidden}
+	ErrNotFound     = &HTTPError{Status: http.StatusNotFound}
+	ErrConflict     = &HTTPError{Status: http.StatusConflict}
+	ErrValidation   = &HTTPError{Status: http.StatusUnprocessableEntity}
+)
+
+// ErrorMapper picks the status an error is answered with, reporting false
+// for errors it does not recognize.
+type ErrorMapper func(err error) (status int, ok bool)
+
+// ErrorMapping turns the errors handlers return into problem responses,
+// localized with the message catalogs of the application.
+// Mappers are tried in the order they were added; an HTTPError anywhere in
+// the chain is used next, then a body over the size limit answers 413, and
+// anything else is answered with 500.
+type ErrorMapping struct {
+	mu        sync.RWMutex
+	mappers   []ErrorMapper
+	formatter ProblemFormatter
+	messages  *messages.Catalogs
+}
+
+func NewErrorMapping() *ErrorMapping {
+	return &ErrorMapping{messages: messages.NewCatalogs()}
+}
+
+// Messages returns the catalogs problems are localized with. A nil
+// mapping only has the built-in translations.
+func (m *ErrorMapping) Messages() *messages.Catalogs {
+	if m == nil {
+		return nil
+	}
+	return m.messages
+}
+
+func (m *ErrorMapping) Add(fn ErrorMapper) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mappers = append(m.mappers, fn)
+}
+
+// SetFormatter replaces the problem+json rendering of every problem the
+// application answers with.
+func (m *ErrorMapping) SetFormatter(fn ProblemFormatter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.formatter = fn
+}
+
+// Map answers errors matching target, as reported by errors.Is, with
+// status.
+func (m *ErrorMapping) Map(target error, status int) {
+	m.Add(func(err error) (int, bool) {
+		return status, errors.Is(err, target)
+	})
+}
+
+// Status returns the status err maps to, and whether it was expected. An
+// unexpected error gets 500 and should be logged.
+func (m *ErrorMapping) Status(err error) (int, bool) {
+	if m != nil {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		for _, mapper := range m.mappers {
+			if status, ok := mapper(err); ok {
+				return status, true
+			}
+		}
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Status, true
+	}
+	// A body cut off by the size limit while the handler streamed it.
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return http.StatusRequestEntityTooLarge, true
+	}
+	return http.StatusInternalServerError, false
+}
+
+// Problem builds the problem response for err. Only the Detail of an
+// HTTPError answering with the same status reaches the client, since the
+// message of any other error may be internal.
+func (m *ErrorMapping) Problem(err error, r *http.Request, requestID string) (*IActionResult, bool) {
+	status, expected := m.Status(err)
+
+	problem := ProblemDetails{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Instance:  r.URL.Path,
+		RequestID: requestID,
+	}
+	var httpErr *HTTPError
+	var maxErr *http.MaxBytesError
+	switch {
+	case !expected:
+		problem.Detail = "An unexpected error occurred."
+	case errors.As(err, &httpErr) && httpErr.Status == status && httpErr.Detail != "":
+		problem.Detail = httpErr.Detail
+	case status == http.StatusRequestEntityTooLarge && errors.As(err, &maxErr):
+		problem.Detail = "The request body is too large."
+	default:
+		problem.Detail = http.StatusText(status)
+	}
+
+	return m.Respond(r, problem), expected
+}
+
+// Respond renders problem for r, with its title and detail in the
+// language the client asked for. A nil mapping renders problem+json.
+func (m *ErrorMapping) Respond(r *http.Request, problem ProblemDetails) *IActionResult {
+	catalogs := m.Messages()
+	lang := catalogs.Negotiate(r.Header.Get("Accept-Language"))
+	problem.Title = catalogs.Localize(lang, problem.Title)
+	problem.Detail = catalogs.Localize(lang, problem.Detail)
+
+	var formatter ProblemFormatter
+	if m != nil {
+		m.mu.RLock()
+		formatter = m.formatter
+		m.mu.RUnlock()
+	}
+	if formatter != nil {
+		return formatter(r, problem)
+	}
+	return Response(problem.Status, problem).MtType(types.ApplicationProblemJSON)
+}
+
+// Complete passes a problem returned by a handler, such as the ones built
+// by Problem and ValidationProblem, through Respond, filling in what only
+// the request knows. Other responses are returned unchanged.
+func (m *ErrorMapping) Complete(r *http.Request, requestID string, response interface{}) interface{} {
+	result, ok := response.(*IActionResult)
+	if !ok {
+		return response
+	}
+	problem, ok := result.content.(ProblemDetails)
+	if !ok {
+		return response
+	}
+
+	if problem.Instance == "" {
+		problem.Instance = r.URL.Path
+	}
+	if problem.RequestID == "" {
+		problem.RequestID = requestID
+	}
+
+	completed := m.Respond(r, problem)
+	for key, value := range result.headers {
+		if _, set := completed.headers[key]; !set {
+			completed.SetHeader(key, value)
+		}
+	}
+	completed.cookies = append(completed.cookies, result.cookies...)
+	return completed
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"path/filepath"
+	"sort"
 	"text/template"
 
 	"github.com/AbrahamBass/swiftapi/internal/types"
@@ -36,19 +37,39 @@ func Response(statusCode int, content interface{}) *IActionResult {
 }
 
 func Problem(detail string) *IActionResult {
-	body := map[string]interface{}{
-		"detail": detail,
-		"status": http.StatusInternalServerError,
+	problem := ProblemDetails{
+		Type:   "about:blank",
+		Title:  http.StatusText(http.StatusInternalServerError),
+		Status: http.StatusInternalServerError,
+		Detail: detail,
 	}
-	return InternalServerError(body).MtType(types.ApplicationProblemJSON)
+	return InternalServerError(problem).MtType(types.ApplicationProblemJSON)
 }
 
+// ValidationProblem answers 422 with one error per key of errors, in the
+// same shape the binder reports invalid requests with.
 func ValidationProblem(errors map[string]string) *IActionResult {
-	body := map[string]interface{}{
-		"errors": errors,
-		"status": http.StatusUnprocessableEntity,
+	keys := make([]string, 0, len(errors))
+	for key := range errors {
+		keys = append(keys, key)
 	}
-	return UnprocessableEntity(body).MtType(types.ApplicationProblemJSON)
+	sort.Strings(keys)
+
+	problem := ProblemDetails{
+		Type:   "about:blank",
+		Title:  http.StatusText(http.StatusUnprocessableEntity),
+		Status: http.StatusUnprocessableEntity,
+		Detail: "The request did not pass validation.",
+		Errors: make([]ProblemError, len(keys)),
+	}
+	for i, key := range keys {
+		problem.Errors[i] = ProblemError{
+			Loc:  []string{key},
+			Msg:  errors[key],
+			Type: types.Invalid,
+		}
+	}
+	return UnprocessableEntity(problem).MtType(types.ApplicationProblemJSON)
 }
 
 func CreatedAt(url string, content interface{}) *IActionResult {
@@ -206,7 +227,8 @@ func (a *IActionResult) SetCrumb(cookie *http.Cookie) *IActionResult {
 func (a *IActionResult) MtType(mt types.MediaType) *IActionResult {
 	switch mt {
 	case types.ApplicationJSON, types.TextPlain, types.TextHTML, types.ApplicationXML,
-		types.OctetStream, types.ApplicationForm, types.MultipartForm:
+		types.OctetStream, types.ApplicationForm, types.MultipartForm,
+		types.ApplicationProblemJSON, types.ApplicationMsgPack:
 		a.mediaType = mt
 	default:
 		panic("invalid media type")
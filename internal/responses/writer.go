@@ -92,6 +92,37 @@ func (rw *ResponseWriter) Send(v interface{}) {
 	}
 }
 
+// Writer returns the underlying writer for handlers that write the
+// response themselves. Writing through it marks the header as sent, so a
+// later Send cannot write a second one.
+func (rw *ResponseWriter) Writer() http.ResponseWriter {
+	return &directWriter{ResponseWriter: rw.W, rw: rw}
+}
+
+type directWriter struct {
+	http.ResponseWriter
+	rw *ResponseWriter
+}
+
+func (w *directWriter) WriteHeader(statusCode int) {
+	w.rw.writeHeader(statusCode)
+}
+
+func (w *directWriter) Write(b []byte) (int, error) {
+	w.rw.writeHeader(http.StatusOK)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *directWriter) Flush() {
+	if fl, ok := w.ResponseWriter.(http.Flusher); ok {
+		fl.Flush()
+	}
+}
+
+func (w *directWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 func (rw *ResponseWriter) writeHeader(statusCode int) {
 	if !rw.headerWritten {
 		rw.W.WriteHeader(statusCode)
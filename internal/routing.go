@@ -1,21 +1,33 @@
 package swiftapi
 
 import (
+	"fmt"
 	"net/http"
+	"reflect"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/AbrahamBass/swiftapi/internal/dependencies"
 	"github.com/AbrahamBass/swiftapi/internal/types"
 
 	"github.com/gorilla/websocket"
 )
 
 type APIRoute struct {
+	name        string
 	path        pathMatcher
 	methods     []string
 	handler     interface{}
+	tags        dependencies.ParamTags
+	bodyLimit   int64
+	memory      int64
+	timeout     time.Duration
 	middlewares []types.Middleware
+	policies    []types.IPolicy
 	isWebSocket bool
 	wsUpgrader  *websocket.Upgrader
+	err         error
 }
 
 func newAPIRoute(
@@ -29,6 +41,7 @@ func newAPIRoute(
 		path:        path,
 		methods:     methods,
 		handler:     handler,
+		tags:        dependencies.ParamTags{},
 		middlewares: []types.Middleware{},
 		isWebSocket: isWebSocket,
 		wsUpgrader:  wsUpgrader,
@@ -39,18 +52,73 @@ func (a *APIRoute) Wrap(middlewares ...types.Middleware) {
 	a.middlewares = append(a.middlewares, middlewares...)
 }
 
+func (a *APIRoute) Name(name string) types.IAPIRoute {
+	a.name = name
+	return a
+}
+
+// Tag attaches struct-tag style options to the handler parameter called
+// param, such as `default:"1"`. Repeated calls for the same parameter add
+// to the tags already set.
+func (a *APIRoute) Tag(param string, tag string) types.IAPIRoute {
+	a.tags[param] = reflect.StructTag(strings.TrimSpace(string(a.tags[param]) + " " + tag))
+	return a
+}
+
+// BodyLimit caps the request body of the route at limit bytes, overriding
+// the router and application limits. A negative limit removes the cap.
+func (a *APIRoute) BodyLimit(limit int64) types.IAPIRoute {
+	a.bodyLimit = limit
+	return a
+}
+
+// MultipartMemory sets how many bytes of a multipart body are kept in
+// memory before file parts spill to temporary files on disk.
+func (a *APIRoute) MultipartMemory(memory int64) types.IAPIRoute {
+	a.memory = memory
+	return a
+}
+
+// Timeout cancels the context of requests to the route after d,
+// overriding the router and application timeouts. A negative d removes
+// the timeout.
+func (a *APIRoute) Timeout(d time.Duration) types.IAPIRoute {
+	a.timeout = d
+	return a
+}
+
+// Require denies requests to the route unless every policy authorizes the
+// principal, after the router's own policies have.
+func (a *APIRoute) Require(policies ...types.IPolicy) types.IAPIRoute {
+	a.policies = append(a.policies, policies...)
+	return a
+}
+
+type apiMount struct {
+	prefix  string
+	handler http.Handler
+}
+
 type APIRouter struct {
+	parent        *APIRouter
+	children      []*APIRouter
+	mounts        []*apiMount
 	prefix        string
 	version       string
 	authorization bool
+	bodyLimit     int64
+	timeout       time.Duration
 	routes        []*APIRoute
 	middlewares   []types.Middleware
+	policies      []types.IPolicy
 }
 
 func newAPIRouter() *APIRouter {
 	return &APIRouter{
 		authorization: false,
 		routes:        []*APIRoute{},
+		children:      []*APIRouter{},
+		mounts:        []*apiMount{},
 		middlewares:   []types.Middleware{},
 	}
 }
@@ -71,6 +139,98 @@ func (a *APIRouter) Secure(authorization bool) {
 	a.authorization = authorization
 }
 
+// Group registers a child router under prefix. Routes of the child live
+// below the parent's path and run the parent's middlewares and security
+// before the child's own.
+func (a *APIRouter) Group(prefix string, rtrg func(types.IAPIRouter)) {
+	child := newAPIRouter()
+	child.parent = a
+	child.prefix = prefix
+	rtrg(child)
+	a.children = append(a.children, child)
+}
+
+// Mount serves a plain http.Handler for every method under prefix. The
+// handler sees the request path with the mount prefix stripped.
+func (a *APIRouter) Mount(prefix string, handler http.Handler) {
+	a.mounts = append(a.mounts, &apiMount{
+		prefix:  staticPrefix(a.basePath() + cleanPath(prefix)),
+		handler: handler,
+	})
+}
+
+func (a *APIRouter) basePath() string {
+	var fullPath strings.Builder
+
+	if a.parent != nil {
+		fullPath.WriteString(a.parent.basePath())
+	}
+
+	if a.version != "" {
+		fullPath.WriteString(cleanPath(a.version))
+	}
+
+	if a.prefix != "" {
+		fullPath.WriteString(cleanPath(a.prefix))
+	}
+
+	return fullPath.String()
+}
+
+func (a *APIRouter) middlewareChain() []types.Middleware {
+	if a.parent == nil {
+		return a.middlewares
+	}
+	return combineMiddlewares(a.parent.middlewareChain(), a.middlewares)
+}
+
+// Require denies requests to every route in the router and its groups
+// unless every policy authorizes the principal.
+func (a *APIRouter) Require(policies ...types.IPolicy) {
+	a.policies = append(a.policies, policies...)
+}
+
+func (a *APIRouter) policyChain() []types.IPolicy {
+	if a.parent == nil {
+		return a.policies
+	}
+	return append(slices.Clip(a.parent.policyChain()), a.policies...)
+}
+
+func (a *APIRouter) secured() bool {
+	return a.authorization || (a.parent != nil && a.parent.secured())
+}
+
+// BodyLimit caps request bodies of every route in the router and its
+// groups at limit bytes, unless a route or group sets its own.
+func (a *APIRouter) BodyLimit(limit int64) {
+	a.bodyLimit = limit
+}
+
+// effectiveBodyLimit returns the limit of the closest router that set
+// one, or 0 when none did.
+func (a *APIRouter) effectiveBodyLimit() int64 {
+	if a.bodyLimit != 0 || a.parent == nil {
+		return a.bodyLimit
+	}
+	return a.parent.effectiveBodyLimit()
+}
+
+// Timeout cancels the context of requests to every route in the router
+// and its groups after d, unless a route or group sets its own.
+func (a *APIRouter) Timeout(d time.Duration) {
+	a.timeout = d
+}
+
+// effectiveTimeout returns the timeout of the closest router that set
+// one, or 0 when none did.
+func (a *APIRouter) effectiveTimeout() time.Duration {
+	if a.timeout != 0 || a.parent == nil {
+		return a.timeout
+	}
+	return a.parent.effectiveTimeout()
+}
+
 func (a *APIRouter) Handle(
 	method string,
 	path string,
@@ -96,21 +256,8 @@ func (a *APIRouter) Stream(
 	)
 }
 
-func (a *APIRouter) buildRoute(path string) pathMatcher {
-	var fullPath strings.Builder
-
-	if a.version != "" {
-		fullPath.WriteString(cleanPath(a.version))
-	}
-
-	if a.prefix != "" {
-		fullPath.WriteString(cleanPath(a.prefix))
-	}
-
-	fullPath.WriteString(cleanPath(path))
-
-	compiledPath := compilePattern(fullPath.String())
-	return compiledPath
+func (a *APIRouter) buildRoute(path string) (pathMatcher, error) {
+	return compilePattern(a.basePath() + cleanPath(path))
 }
 
 func (a *APIRouter) AddRoute(
@@ -118,7 +265,7 @@ func (a *APIRouter) AddRoute(
 	handler interface{},
 	methods ...string,
 ) types.IAPIRoute {
-	compiled := a.buildRoute(path)
+	compiled, err := a.buildRoute(path)
 
 	route := newAPIRoute(
 		compiled,
@@ -127,6 +274,7 @@ func (a *APIRouter) AddRoute(
 		false,
 		nil,
 	)
+	route.err = err
 
 	a.routes = append(a.routes, route)
 	return route
@@ -138,7 +286,7 @@ func (a *APIRouter) AddWebsocketRoute(
 	origin func(r *http.Request) bool,
 	methods ...string,
 ) types.IAPIRoute {
-	compiled := a.buildRoute(path)
+	compiled, err := a.buildRoute(path)
 
 	if origin == nil {
 		origin = func(r *http.Request) bool { return true }
@@ -157,7 +305,38 @@ func (a *APIRouter) AddWebsocketRoute(
 		true,
 		upgrader,
 	)
+	route.err = err
 
 	a.routes = append(a.routes, route)
 	return route
 }
+
+func flattenRouters(routers []*APIRouter) []*APIRouter {
+	flat := make([]*APIRouter, 0, len(routers))
+	for _, router := range routers {
+		flat = append(flat, router)
+		flat = append(flat, flattenRouters(router.children)...)
+	}
+	return flat
+}
+
+func namedRoutes(routers []*APIRouter) (map[string]*APIRoute, error) {
+	named := map[string]*APIRoute{}
+	for _, router := range flattenRouters(routers) {
+		for _, route := range router.routes {
+			if route.name == "" {
+				continue
+			}
+			if other, exists := named[route.name]; exists {
+				return nil, fmt.Errorf(
+					"route name %q is used by both %s and %s",
+					route.name,
+					other.path.original,
+					route.path.original,
+				)
+			}
+			named[route.name] = route
+		}
+	}
+	return named, nil
+}
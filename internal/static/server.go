@@ -0,0 +1,253 @@
+package static
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	c "github.com/AbrahamBass/swiftapi/internal/context"
+	"github.com/AbrahamBass/swiftapi/internal/responses"
+	"github.com/AbrahamBass/swiftapi/internal/types"
+)
+
+type StaticConfig struct {
+	index         string
+	fallback      string
+	precompressed bool
+}
+
+func NewStaticConfig() *StaticConfig {
+	return &StaticConfig{
+		index:         "index.html",
+		fallback:      "",
+		precompressed: false,
+	}
+}
+
+func (c *StaticConfig) Index() string {
+	return c.index
+}
+
+func (c *StaticConfig) Fallback() string {
+	return c.fallback
+}
+
+func (c *StaticConfig) Precompressed() bool {
+	return c.precompressed
+}
+
+func (c *StaticConfig) SetIndex(index string) {
+	c.index = index
+}
+
+func (c *StaticConfig) SetFallback(fallback string) {
+	c.fallback = fallback
+}
+
+func (c *StaticConfig) SetPrecompressed(precompressed bool) {
+	c.precompressed = precompressed
+}
+
+// encodings lists the precompressed variants looked up next to a file,
+// in order of preference.
+var encodings = []struct {
+	name   string
+	suffix string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+type FileServer struct {
+	fsys   fs.FS
+	config types.IStaticConfig
+	errors *responses.ErrorMapping
+	etags  sync.Map
+}
+
+func NewFileServer(fsys fs.FS, config types.IStaticConfig) *FileServer {
+	return &FileServer{
+		fsys:   fsys,
+		config: config,
+	}
+}
+
+// SetErrorMapping renders the errors of the server like every other
+// problem of the application.
+func (s *FileServer) SetErrorMapping(mapping *responses.ErrorMapping) {
+	s.errors = mapping
+}
+
+func (s *FileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Cleaning a rooted path can never climb above "/", so the result is
+	// always inside the file system.
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if name == "" {
+		name = "."
+	}
+
+	if !fs.ValidPath(name) || strings.ContainsAny(name, "\\\x00") {
+		s.error(w, r, fs.ErrNotExist)
+		return
+	}
+
+	info, err := fs.Stat(s.fsys, name)
+	if err == nil && info.IsDir() {
+		// The request path may have had a mount prefix stripped, so the
+		// redirect stays relative to what the client actually asked for.
+		if !strings.HasSuffix(r.URL.Path, "/") && name != "." {
+			w.Header().Set("Location", path.Base(name)+"/")
+			w.WriteHeader(http.StatusMovedPermanently)
+			return
+		}
+
+		name, err = s.index(name)
+	}
+
+	if err != nil {
+		if s.config.Fallback() == "" || !errors.Is(err, fs.ErrNotExist) {
+			s.error(w, r, err)
+			return
+		}
+		name = s.config.Fallback()
+	}
+
+	s.serveFile(w, r, name)
+}
+
+func (s *FileServer) index(dir string) (string, error) {
+	if s.config.Index() == "" {
+		return "", fs.ErrNotExist
+	}
+
+	name := path.Join(dir, s.config.Index())
+	if _, err := fs.Stat(s.fsys, name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+func (s *FileServer) serveFile(w http.ResponseWriter, r *http.Request, name string) {
+	if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+		w.Header().Set("Content-Type", ctype)
+	}
+
+	served := name
+	if s.config.Precompressed() {
+		w.Header().Add("Vary", "Accept-Encoding")
+		accepted := r.Header.Get("Accept-Encoding")
+
+		for _, encoding := range encodings {
+			if !acceptsEncoding(accepted, encoding.name) {
+				continue
+			}
+			if _, err := fs.Stat(s.fsys, name+encoding.suffix); err == nil {
+				served = name + encoding.suffix
+				w.Header().Set("Content-Encoding", encoding.name)
+				break
+			}
+		}
+	}
+
+	file, err := s.fsys.Open(served)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	content, ok := file.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			s.error(w, r, err)
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+
+	etag, err := s.etag(served, info, content)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", etag)
+
+	http.ServeContent(w, r, name, info.ModTime(), content)
+}
+
+// etag derives a validator from size and modification time when the file
+// system reports one. Embedded files have no modification time, so their
+// content is hashed once and remembered, as it can never change.
+func (s *FileServer) etag(name string, info fs.FileInfo, content io.ReadSeeker) (string, error) {
+	if !info.ModTime().IsZero() {
+		return fmt.Sprintf(`W/"%x-%x"`, info.Size(), info.ModTime().UnixNano()), nil
+	}
+
+	if cached, ok := s.etags.Load(name); ok {
+		return cached.(string), nil
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	etag := `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+	s.etags.Store(name, etag)
+	return etag, nil
+}
+
+// error answers err as a problem. Headers set for the file, such as its
+// Content-Type, do not apply to the problem and are dropped.
+func (s *FileServer) error(w http.ResponseWriter, r *http.Request, err error) {
+	problem := responses.ProblemDetails{
+		Type:      "about:blank",
+		Instance:  r.URL.Path,
+		RequestID: c.RequestID(r),
+	}
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		problem.Status = http.StatusNotFound
+	case errors.Is(err, fs.ErrPermission):
+		problem.Status = http.StatusForbidden
+	default:
+		problem.Status = http.StatusInternalServerError
+		problem.Detail = "An unexpected error occurred."
+	}
+	problem.Title = http.StatusText(problem.Status)
+
+	for _, header := range []string{"Content-Type", "Content-Encoding", "ETag"} {
+		w.Header().Del(header)
+	}
+	responses.NewResponseWriter(w).Send(s.errors.Respond(r, problem))
+}
+
+func acceptsEncoding(header, encoding string) bool {
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), encoding) {
+			continue
+		}
+		return strings.ReplaceAll(strings.TrimSpace(params), " ", "") != "q=0"
+	}
+	return false
+}
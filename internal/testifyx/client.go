@@ -20,7 +20,11 @@ type TestClient struct {
 }
 
 func NewTestClient(app types.IApplication, tb testing.TB) *TestClient {
-	return &TestClient{handler: app.Mux(), tb: tb}
+	handler, err := app.Handler()
+	if err != nil {
+		tb.Fatalf("❌ Error compiling application: %v", err)
+	}
+	return &TestClient{handler: handler, tb: tb}
 }
 
 type RequestBuilder struct {
@@ -144,7 +148,7 @@ func (c *TestClient) PatchJSON(path string, body interface{}) *RequestBuilder {
 
 func (c *TestClient) sendJSON(method, path string, body interface{}) *RequestBuilder {
 	if body == nil {
-		c.tb.Fatal("JSON body no puede ser nil")
+		c.tb.Fatal("JSON body must not be nil")
 	}
 
 	bodyBytes, err := json.Marshal(body)
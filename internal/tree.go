@@ -0,0 +1,284 @@
+package swiftapi
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// muxEntry is one route registered on a node. Parameter names belong to
+// the entry rather than the node, so routes spelling the same position
+// differently ({id} and {itemID}) still share a node.
+type muxEntry struct {
+	methods []string
+	params  []string
+	pattern string
+	handler http.HandlerFunc
+}
+
+type node struct {
+	segment  string
+	static   map[string]*node
+	params   []*node
+	catchAll *node
+	regex    *regexp.Regexp
+	groups   []int
+	entries  []*muxEntry
+}
+
+func newNode(segment string) *node {
+	return &node{
+		segment: segment,
+		static:  map[string]*node{},
+		params:  []*node{},
+	}
+}
+
+func splitPath(p string) []string {
+	return strings.Split(strings.TrimPrefix(p, "/"), "/")
+}
+
+func isParamSegment(segment string) bool {
+	return strings.Contains(segment, "{")
+}
+
+// segmentShape is the segment with its parameter names left out, which is
+// what decides whether two segments match the same values.
+func segmentShape(tokens []segmentToken) string {
+	var shape strings.Builder
+	for _, token := range tokens {
+		switch {
+		case !token.isParam():
+			shape.WriteString(token.literal)
+		case token.catchAll:
+			shape.WriteString("{...}")
+		case token.pattern != "":
+			shape.WriteString("{:" + token.pattern + "}")
+		default:
+			shape.WriteString("{}")
+		}
+	}
+	return shape.String()
+}
+
+// compileSegment builds the matcher for a segment holding one or more
+// placeholders. A segment that is exactly one unconstrained {name} needs
+// no regex: any non-empty value matches. Groups are named by position
+// since the names themselves vary between the routes sharing the node.
+func compileSegment(tokens []segmentToken) (*regexp.Regexp, []int, error) {
+	if len(tokens) == 1 && tokens[0].pattern == "" {
+		return nil, nil, nil
+	}
+
+	positional := slices.Clone(tokens)
+	count := 0
+	for i := range positional {
+		if positional[i].isParam() {
+			positional[i].name = "p" + strconv.Itoa(count)
+			count++
+		}
+	}
+
+	regex, err := regexp.Compile("^" + tokensToRegex(positional) + "$")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	groups := make([]int, count)
+	for i := range groups {
+		groups[i] = regex.SubexpIndex("p" + strconv.Itoa(i))
+	}
+	return regex, groups, nil
+}
+
+// insert adds entry under pattern. Two entries on the same node may not
+// share a method, since only the first would ever be served; nil methods,
+// as used by mounts, share every method.
+func (n *node) insert(pattern string, entry *muxEntry) error {
+	current := n
+	for _, segment := range splitPath(pattern) {
+		if !isParamSegment(segment) {
+			current = current.staticChild(segment)
+			continue
+		}
+
+		tokens, err := parseSegment(segment)
+		if err != nil {
+			return err
+		}
+		if current, err = current.paramChild(tokens); err != nil {
+			return err
+		}
+
+		for _, token := range tokens {
+			if token.isParam() {
+				entry.params = append(entry.params, token.name)
+			}
+		}
+	}
+
+	for _, other := range current.entries {
+		if entry.overlaps(other) {
+			return fmt.Errorf("%s conflicts with %s for the same methods", pattern, other.pattern)
+		}
+	}
+
+	entry.pattern = pattern
+	current.entries = append(current.entries, entry)
+	return nil
+}
+
+func (n *node) staticChild(segment string) *node {
+	if next, ok := n.static[segment]; ok {
+		return next
+	}
+	next := newNode(segment)
+	n.static[segment] = next
+	return next
+}
+
+func (n *node) paramChild(tokens []segmentToken) (*node, error) {
+	shape := segmentShape(tokens)
+
+	if tokens[0].catchAll {
+		if n.catchAll == nil {
+			n.catchAll = newNode(shape)
+		}
+		return n.catchAll, nil
+	}
+
+	for _, next := range n.params {
+		if next.segment == shape {
+			return next, nil
+		}
+	}
+
+	regex, groups, err := compileSegment(tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	next := newNode(shape)
+	next.regex, next.groups = regex, groups
+
+	// Segments with a literal part or a constraint are more specific than
+	// a bare {name}, so they are tried before any bare parameter.
+	at := len(n.params)
+	if next.regex != nil {
+		at = slices.IndexFunc(n.params, func(p *node) bool { return p.regex == nil })
+		if at < 0 {
+			at = len(n.params)
+		}
+	}
+	n.params = slices.Insert(n.params, at, next)
+	return next, nil
+}
+
+func (e *muxEntry) overlaps(other *muxEntry) bool {
+	if e.methods == nil || other.methods == nil {
+		return true
+	}
+	return slices.ContainsFunc(e.methods, func(method string) bool {
+		return slices.Contains(other.methods, method)
+	})
+}
+
+func (n *node) match(method string) *muxEntry {
+	for _, entry := range n.entries {
+		if entry.methods == nil || slices.Contains(entry.methods, method) {
+			return entry
+		}
+	}
+	return nil
+}
+
+// allowed lists every method served by the given nodes, including the
+// HEAD and OPTIONS responses the mux synthesizes on its own.
+func allowed(nodes []*node) []string {
+	methods := []string{http.MethodOptions}
+	for _, n := range nodes {
+		for _, entry := range n.entries {
+			for _, method := range entry.methods {
+				if !slices.Contains(methods, method) {
+					methods = append(methods, method)
+				}
+			}
+		}
+	}
+
+	if slices.Contains(methods, http.MethodGet) && !slices.Contains(methods, http.MethodHead) {
+		methods = append(methods, http.MethodHead)
+	}
+
+	sort.Strings(methods)
+	return methods
+}
+
+// lookup walks the tree for the given path and hands every node with
+// routes that matches it to visit, most specific first, until visit
+// returns true. Static segments take priority over parameters, and
+// parameters over a catch-all.
+func (n *node) lookup(path string, visit func(leaf *node, values []string) bool) {
+	n.find(splitPath(path), make([]string, 0, 4), visit)
+}
+
+func (n *node) find(segments []string, values []string, visit func(*node, []string) bool) bool {
+	if len(segments) == 0 {
+		return len(n.entries) > 0 && visit(n, values)
+	}
+
+	segment, rest := segments[0], segments[1:]
+
+	if next, ok := n.static[segment]; ok {
+		if next.find(rest, values, visit) {
+			return true
+		}
+	}
+
+	// Parameters never match an empty segment; only a catch-all may.
+	if segment != "" {
+		for _, next := range n.params {
+			if next.findParam(segment, rest, values, visit) {
+				return true
+			}
+		}
+	}
+
+	if n.catchAll != nil && len(n.catchAll.entries) > 0 {
+		return visit(n.catchAll, append(values, strings.Join(segments, "/")))
+	}
+
+	return false
+}
+
+func (n *node) findParam(segment string, rest []string, values []string, visit func(*node, []string) bool) bool {
+	if n.regex == nil {
+		return n.find(rest, append(values, segment), visit)
+	}
+
+	matches := n.regex.FindStringSubmatch(segment)
+	if matches == nil {
+		return false
+	}
+	for _, group := range n.groups {
+		values = append(values, matches[group])
+	}
+	return n.find(rest, values, visit)
+}
+
+// bind pairs the values collected by lookup with the entry's own
+// parameter names.
+func (e *muxEntry) bind(values []string) map[string]string {
+	if len(e.params) == 0 {
+		return nil
+	}
+	params := make(map[string]string, len(e.params))
+	for i, name := range e.params {
+		params[name] = values[i]
+	}
+	return params
+}
@@ -0,0 +1,80 @@
+package swiftapi
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// benchmarkSizes are the route counts lookups are measured at. Lookup
+// cost should stay flat as the count grows.
+var benchmarkSizes = []int{10, 100, 1000, 10000}
+
+// benchmarkTree builds a tree of routes routes, cycling through a static,
+// a parameter, a constrained parameter and a catch-all route per
+// resource, so lookups have siblings to skip at every level.
+func benchmarkTree(b *testing.B, routes int) *node {
+	b.Helper()
+
+	tree := newNode("")
+	for i := 0; i < routes; i++ {
+		resource := i / 4
+		pattern := [...]string{
+			fmt.Sprintf("/api/v1/resource%d", resource),
+			fmt.Sprintf("/api/v1/resource%d/{id}", resource),
+			fmt.Sprintf("/api/v1/resource%d/{id:int}/items/{item}", resource),
+			fmt.Sprintf("/assets%d/{filepath...}", resource),
+		}[i%4]
+
+		entry := &muxEntry{methods: []string{http.MethodGet}}
+		if err := tree.insert(pattern, entry); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return tree
+}
+
+// benchmarkLookup measures looking up the path built by path for the
+// last resource fully registered in each tree size.
+func benchmarkLookup(b *testing.B, path func(resource int) string) {
+	for _, routes := range benchmarkSizes {
+		b.Run(fmt.Sprintf("routes=%d", routes), func(b *testing.B) {
+			tree := benchmarkTree(b, routes)
+			target := path(routes/4 - 1)
+
+			var hit bool
+			visit := func(leaf *node, values []string) bool {
+				hit = leaf.match(http.MethodGet) != nil
+				return hit
+			}
+
+			if tree.lookup(target, visit); !hit {
+				b.Fatalf("no route for %s", target)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				tree.lookup(target, visit)
+			}
+		})
+	}
+}
+
+func BenchmarkLookupStatic(b *testing.B) {
+	benchmarkLookup(b, func(resource int) string {
+		return fmt.Sprintf("/api/v1/resource%d", resource)
+	})
+}
+
+func BenchmarkLookupParam(b *testing.B) {
+	benchmarkLookup(b, func(resource int) string {
+		return fmt.Sprintf("/api/v1/resource%d/123/items/abc", resource)
+	})
+}
+
+func BenchmarkLookupCatchAll(b *testing.B) {
+	benchmarkLookup(b, func(resource int) string {
+		return fmt.Sprintf("/assets%d/css/site/main.css", resource)
+	})
+}
@@ -1,12 +1,17 @@
 package types
 
 import (
+	"context"
 	"crypto/tls"
+	"io/fs"
 	"net/http"
 	"net/url"
+	"reflect"
 	"sync"
 	"time"
 
+	"github.com/AbrahamBass/swiftapi/internal/auth"
+
 	"github.com/gorilla/csrf"
 	"go.uber.org/dig"
 	"go.uber.org/zap"
@@ -20,12 +25,34 @@ type ILogger interface {
 
 type IStaticFile interface {
 	ILogger
-	AddStaticFile(prefix, dir string)
+	AddStaticFile(prefix string, handler http.Handler)
 	StaticFileExists(prefix string) bool
 }
 
+type IStaticConfig interface {
+	Index() string
+	Fallback() string
+	Precompressed() bool
+	SetIndex(index string)
+	SetFallback(fallback string)
+	SetPrecompressed(precompressed bool)
+}
+
 type IAPIRoute interface {
 	Wrap(middlewares ...Middleware)
+	Name(name string) IAPIRoute
+	Tag(param string, tag string) IAPIRoute
+	BodyLimit(limit int64) IAPIRoute
+	MultipartMemory(memory int64) IAPIRoute
+	Timeout(d time.Duration) IAPIRoute
+	Require(policies ...IPolicy) IAPIRoute
+}
+
+// IPolicy decides whether the principal a request was authenticated as
+// may use a route. A non-nil error denies the request with 403, or with
+// the status of an HTTPError.
+type IPolicy interface {
+	Authorize(scope IRequestScope, principal *auth.Principal) error
 }
 
 type IAPIWebsocketRoute interface {
@@ -36,11 +63,16 @@ type IAPIRouter interface {
 	Stream(path string, handler interface{}, origin func(r *http.Request) bool) IAPIRoute
 	Handle(method string, path string, handler interface{}) IAPIRoute
 	Secure(secure bool)
+	Require(policies ...IPolicy)
 	AddRoute(path string, handler interface{}, methods ...string) IAPIRoute
 	AddWebsocketRoute(path string, handler interface{}, origin func(r *http.Request) bool, methods ...string) IAPIRoute
 	Wrap(middlewares ...Middleware)
 	Prefix(prefix string)
 	Version(version string)
+	Group(prefix string, rtrg func(IAPIRouter))
+	Mount(prefix string, handler http.Handler)
+	BodyLimit(limit int64)
+	Timeout(d time.Duration)
 }
 
 type IInclude interface {
@@ -52,11 +84,22 @@ type IJWTConfig interface {
 	Algorithms() []string
 	Audience() []string
 	Issuer() []string
+	PublicKeys() map[string]interface{}
+	KeySet() IKeySet
 
 	SetKey(key string)
 	SetAlgorithms(algorithms []string)
 	SetAudience(audience []string)
 	SetIssuer(issuer []string)
+	AddPublicKey(kid string, key interface{})
+	SetKeySet(keySet IKeySet)
+}
+
+// IKeySet looks up token verification keys by their kid, which is "" for
+// tokens that do not name one. ctx is the context of the request being
+// authenticated.
+type IKeySet interface {
+	Key(ctx context.Context, kid string) (interface{}, error)
 }
 
 type IJwt interface {
@@ -116,10 +159,24 @@ type ICORSConfigurer interface {
 type IApplication interface {
 	ILogger
 	IInclude
+	IStaticFile
 	IJwt
 	IMiddleware
 	Build(port int) IApplication
 	Mux() http.Handler
+	Handler() (http.Handler, error)
+	ReflectionOnly()
+	AddConverter(typ reflect.Type, fn func(value string) (any, error))
+	TimeLayouts(layouts ...string)
+	AddDecoder(mediaType string, fn func(data []byte, v any) error)
+	AddProvider(fn interface{})
+	BodyLimit(limit int64)
+	MultipartMemory(memory int64)
+	Timeout(d time.Duration)
+	MapError(target error, status int)
+	AddErrorMapper(fn func(err error) (status int, ok bool))
+	Messages(lang string, catalog map[string]string)
+	URLFor(name string, params map[string]string) (string, error)
 	Di() IContainerBuilder // Agregamos los builders
 	Include() IIncludeBuilder
 	CSRF() ICSRFBuilder
@@ -128,11 +185,14 @@ type IApplication interface {
 	Sanitization() ISanitizationBuilder
 	Cors() ICORSBuilder
 	HTTPSRedirect() IHTTPSRedirectBuilder
+	Static(prefix string, fsys fs.FS) IStaticBuilder
 }
 
 type IContainerBuilder interface {
 	// Métodos de Container
 	Provide(instance interface{}) IContainerBuilder
+	Scoped(constructor interface{}) IContainerBuilder
+	Transient(constructor interface{}) IContainerBuilder
 	Apply() IApplication
 }
 
@@ -141,6 +201,12 @@ type IDigContainer interface {
 	Invoke(function interface{}, opts ...dig.InvokeOption) error
 }
 
+type IServiceContainer interface {
+	IDigContainer
+	Scoped(constructor interface{}) error
+	Transient(constructor interface{}) error
+}
+
 type IIncludeBuilder interface {
 	Add(rtrg func(IAPIRouter)) IIncludeBuilder
 	Apply() IApplication
@@ -151,6 +217,9 @@ type IJWTBuilder interface {
 	Algorithms(algorithms []string) IJWTBuilder
 	Audience(audience []string) IJWTBuilder
 	Issue(issuer []string) IJWTBuilder
+	PublicKey(kid string, pem string) IJWTBuilder
+	JWKSFile(path string, refresh time.Duration) IJWTBuilder
+	JWKSURL(url string, refresh time.Duration) IJWTBuilder
 	Apply() IApplication
 }
 
@@ -172,6 +241,13 @@ type IHTTPSRedirectBuilder interface {
 	Apply() IApplication
 }
 
+type IStaticBuilder interface {
+	Index(file string) IStaticBuilder
+	Fallback(file string) IStaticBuilder
+	Precompressed(enabled bool) IStaticBuilder
+	Apply() IApplication
+}
+
 type ISanitizationBuilder interface {
 	Apply() IApplication
 }
@@ -210,6 +286,8 @@ type IRequestScope interface {
 	SetHeader(key, value string)
 	SetCrumb(cookie *http.Cookie)
 	SetBaggage(key string, value any)
+	SetPrincipal(p *auth.Principal)
+	Principal() (*auth.Principal, bool)
 
 	SecureChannel() *tls.ConnectionState
 	Hostname() string
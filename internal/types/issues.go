@@ -17,4 +17,18 @@ const (
 	BodyRead        IssueType = "body_read"        // Error al leer el cuerpo
 	InvalidType     IssueType = "invalid_type"     // Tipo no válido
 	UnsupportedType IssueType = "unsupported_type" // Tipo no soportado
+	TooSmall        IssueType = "too_small"        // Número menor al mínimo
+	TooLarge        IssueType = "too_large"        // Número mayor al máximo
+	TooShort        IssueType = "too_short"        // Longitud menor a la permitida
+	TooLong         IssueType = "too_long"         // Longitud mayor a la permitida
+	InvalidFormat   IssueType = "invalid_format"   // Formato no válido
+	NotAllowed      IssueType = "not_allowed"      // Valor fuera de las opciones
+	PatternMismatch IssueType = "pattern_mismatch" // No coincide con el patrón
+	CustomRule      IssueType = "custom_rule"      // Falla de validador propio
+
+	UnsupportedMediaType IssueType = "unsupported_media_type" // Content-Type sin decodificador
+	BodyTooLarge         IssueType = "body_too_large"         // Cuerpo mayor al límite
+
+	Unauthenticated IssueType = "unauthenticated" // Solicitud sin principal
+	InvalidClaims   IssueType = "invalid_claims"  // Claims que no encajan en el tipo
 )
@@ -11,4 +11,5 @@ const (
 	OctetStream            MediaType = "application/octet-stream"
 	ApplicationForm        MediaType = "application/x-www-form-urlencoded"
 	MultipartForm          MediaType = "multipart/form-data"
+	ApplicationMsgPack     MediaType = "application/msgpack"
 )
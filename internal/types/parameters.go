@@ -4,6 +4,12 @@ import "mime/multipart"
 
 type UploadFile *multipart.FileHeader
 
+// FileStream reads the parts of a multipart body one at a time, straight
+// from the connection, instead of having it parsed up front.
+type FileStream struct {
+	*multipart.Reader
+}
+
 type Location string
 
 const (
@@ -24,4 +30,5 @@ const (
 	TagForm    TagType = "Silk"
 	TagService TagType = "Dependency"
 	TagContext TagType = "Scope"
+	TagClaims  TagType = "Claims"
 )
@@ -1,6 +1,8 @@
 package swiftapi
 
 import (
+	"fmt"
+	"net/url"
 	"path"
 	"regexp"
 	"strings"
@@ -25,6 +27,16 @@ func cleanPath(p string) string {
 	return np
 }
 
+// staticPrefix normalizes a mount prefix to a cleaned path without a
+// trailing slash, keeping "/" for the root.
+func staticPrefix(prefix string) string {
+	p := cleanPath(prefix)
+	if p != "/" {
+		p = strings.TrimSuffix(p, "/")
+	}
+	return p
+}
+
 type pathMatcher struct {
 	original   string
 	regex      *regexp.Regexp
@@ -40,32 +52,230 @@ func NewPathMatcher(original string, regex *regexp.Regexp, paramName []string) p
 }
 
 func (rp *pathMatcher) Match(path string) (bool, map[string]string) {
+	if rp.regex == nil {
+		return false, nil
+	}
+
 	matches := rp.regex.FindStringSubmatch(path)
 	if matches == nil {
 		return false, nil
 	}
 
 	params := make(map[string]string)
-	for i, name := range rp.paramNames {
-		params[name] = matches[i+1]
+	for _, name := range rp.paramNames {
+		params[name] = matches[rp.regex.SubexpIndex(name)]
 	}
 	return true, params
 }
 
-func compilePattern(pattern string) pathMatcher {
-	regexPattern := "^" + strings.ReplaceAll(pattern, "{", "(?P<") + "$"
-	regexPattern = strings.ReplaceAll(regexPattern, "}", ">[^/]+)")
+// Build fills the pattern placeholders with params and checks the result
+// against the compiled regex, so values that break a constraint are
+// rejected rather than producing a URL the router would never match.
+func (rp *pathMatcher) Build(params map[string]string) (string, error) {
+	if rp.regex == nil {
+		return "", fmt.Errorf("route %s did not compile", rp.original)
+	}
+
+	var built strings.Builder
+
+	for _, segment := range splitPath(rp.original) {
+		built.WriteString("/")
+
+		tokens, err := parseSegment(segment)
+		if err != nil {
+			return "", err
+		}
+
+		for _, token := range tokens {
+			if !token.isParam() {
+				built.WriteString(token.literal)
+				continue
+			}
+
+			value, ok := params[token.name]
+			if !ok {
+				return "", fmt.Errorf("missing parameter %q for route %s", token.name, rp.original)
+			}
+
+			if token.catchAll {
+				parts := strings.Split(value, "/")
+				for i, part := range parts {
+					parts[i] = url.PathEscape(part)
+				}
+				built.WriteString(strings.Join(parts, "/"))
+			} else {
+				built.WriteString(url.PathEscape(value))
+			}
+		}
+	}
+
+	path := built.String()
+	if unescaped, err := url.PathUnescape(path); err != nil || !rp.regex.MatchString(unescaped) {
+		return "", fmt.Errorf("parameters %v do not satisfy route %s", params, rp.original)
+	}
+
+	return path, nil
+}
+
+var paramConstraints = map[string]string{
+	"int":   `-?[0-9]+`,
+	"uint":  `[0-9]+`,
+	"float": `-?[0-9]+(?:\.[0-9]+)?`,
+	"alpha": `[A-Za-z]+`,
+	"alnum": `[A-Za-z0-9]+`,
+	"uuid":  `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
+}
+
+var typeName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+type segmentToken struct {
+	literal  string
+	name     string
+	pattern  string
+	catchAll bool
+}
+
+func (t segmentToken) isParam() bool {
+	return t.name != ""
+}
+
+// parseSegment splits a path segment into literal text and placeholders.
+// Placeholders take the forms {name}, {name:constraint} and {name...};
+// a constraint is either one of paramConstraints or a regular expression,
+// which may itself contain braces. A constraint that is a single word
+// must be one of paramConstraints.
+func parseSegment(segment string) ([]segmentToken, error) {
+	var tokens []segmentToken
+
+	rest := segment
+	for rest != "" {
+		start := strings.Index(rest, "{")
+		if start < 0 {
+			tokens = append(tokens, segmentToken{literal: rest})
+			break
+		}
+		if start > 0 {
+			tokens = append(tokens, segmentToken{literal: rest[:start]})
+		}
+
+		depth, end := 0, -1
+		for i := start; i < len(rest) && end < 0; i++ {
+			switch rest[i] {
+			case '{':
+				depth++
+			case '}':
+				depth--
+				if depth == 0 {
+					end = i
+				}
+			}
+		}
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed placeholder in path segment %q", segment)
+		}
+
+		token, err := parsePlaceholder(rest[start+1 : end])
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, token)
+		rest = rest[end+1:]
+	}
+
+	return tokens, nil
+}
+
+func parsePlaceholder(placeholder string) (segmentToken, error) {
+	if name, ok := strings.CutSuffix(placeholder, "..."); ok {
+		return segmentToken{name: name, pattern: ".*", catchAll: true}, nil
+	}
 
-	re := regexp.MustCompile(regexPattern)
-	paramNames := re.SubexpNames()[1:]
+	name, constraint, constrained := strings.Cut(placeholder, ":")
+	if !constrained {
+		return segmentToken{name: name}, nil
+	}
 
-	return NewPathMatcher(pattern, re, paramNames)
+	if pattern, ok := paramConstraints[constraint]; ok {
+		return segmentToken{name: name, pattern: pattern}, nil
+	}
+
+	// A misspelled type must not be taken as a regex matching the word.
+	if typeName.MatchString(constraint) {
+		return segmentToken{}, fmt.Errorf("unknown type %q for path parameter %q", constraint, name)
+	}
+
+	if _, err := regexp.Compile(constraint); err != nil {
+		return segmentToken{}, fmt.Errorf("invalid constraint for path parameter %q: %v", name, err)
+	}
+	return segmentToken{name: name, pattern: constraint}, nil
 }
 
-func combineMiddlewares(global, group, route []types.Middleware) []types.Middleware {
-	combined := make([]types.Middleware, 0, len(global)+len(group)+len(route))
-	combined = append(combined, global...)
-	combined = append(combined, group...)
-	combined = append(combined, route...)
+func tokensToRegex(tokens []segmentToken) string {
+	var pattern strings.Builder
+	for _, token := range tokens {
+		if !token.isParam() {
+			pattern.WriteString(regexp.QuoteMeta(token.literal))
+			continue
+		}
+
+		expr := token.pattern
+		if expr == "" {
+			expr = "[^/]+"
+		}
+		pattern.WriteString("(?P<" + token.name + ">(?:" + expr + "))")
+	}
+	return pattern.String()
+}
+
+// compilePattern builds the matcher for a route pattern. On error the
+// matcher only keeps the original pattern, for reporting.
+func compilePattern(pattern string) (pathMatcher, error) {
+	segments := splitPath(pattern)
+
+	var regexPattern strings.Builder
+	var paramNames []string
+
+	regexPattern.WriteString("^")
+	for i, segment := range segments {
+		tokens, err := parseSegment(segment)
+		if err != nil {
+			return pathMatcher{original: pattern}, err
+		}
+
+		for _, token := range tokens {
+			if token.catchAll && (i != len(segments)-1 || len(tokens) != 1) {
+				return pathMatcher{original: pattern}, fmt.Errorf("catch-all parameter %q must be the whole last segment of %q", token.name, pattern)
+			}
+			if token.isParam() {
+				paramNames = append(paramNames, token.name)
+			}
+		}
+
+		regexPattern.WriteString("/")
+		regexPattern.WriteString(tokensToRegex(tokens))
+	}
+	regexPattern.WriteString("$")
+
+	re, err := regexp.Compile(regexPattern.String())
+	if err != nil {
+		return pathMatcher{original: pattern}, err
+	}
+
+	return NewPathMatcher(pattern, re, paramNames), nil
+}
+
+// combineMiddlewares concatenates middleware chains from the outermost
+// (global) to the innermost (route) level into a new slice.
+func combineMiddlewares(chains ...[]types.Middleware) []types.Middleware {
+	size := 0
+	for _, chain := range chains {
+		size += len(chain)
+	}
+
+	combined := make([]types.Middleware, 0, size)
+	for _, chain := range chains {
+		combined = append(combined, chain...)
+	}
 	return combined
 }
@@ -0,0 +1,387 @@
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/AbrahamBass/swiftapi/internal/types"
+
+	"github.com/google/uuid"
+)
+
+// Func is a custom validation rule. It receives the bound value and the
+// text after "=" in the tag, and returns an error describing the failure.
+type Func func(value any, param string) error
+
+// Failure is one rule that a value did not satisfy. Msg is Format
+// rendered with Args; the two are kept apart so that callers can
+// translate the message.
+type Failure struct {
+	Loc    []string
+	Msg    string
+	Type   types.IssueType
+	Format string
+	Args   []any
+}
+
+func newFailure(loc []string, typ types.IssueType, format string, args ...any) Failure {
+	msg := format
+	if len(args) > 0 {
+		msg = fmt.Sprintf(format, args...)
+	}
+	return Failure{Loc: loc, Msg: msg, Type: typ, Format: format, Args: args}
+}
+
+type rule struct {
+	name  string
+	param string
+	regex *regexp.Regexp
+	fn    Func
+}
+
+var (
+	custom sync.Map
+	parsed sync.Map
+)
+
+var builtin = []string{"required", "omitempty", "min", "max", "len", "email", "url", "uuid", "oneof", "regex"}
+
+// RegisterValidator makes fn available as the rule name in validate tags.
+// It must be called before the application builds its handler.
+func RegisterValidator(name string, fn Func) {
+	if name == "" || strings.ContainsAny(name, ",=") {
+		panic(fmt.Sprintf("invalid validator name %q", name))
+	}
+	if slices.Contains(builtin, name) {
+		panic(fmt.Sprintf("validator %q is built in and cannot be replaced", name))
+	}
+	custom.Store(name, fn)
+	parsed.Clear()
+}
+
+// parse splits a validate tag into rules. Rules are separated by commas;
+// regex takes the rest of the tag as its pattern so it may contain them.
+func parse(tag string) ([]rule, error) {
+	if cached, ok := parsed.Load(tag); ok {
+		return cached.([]rule), nil
+	}
+
+	var rules []rule
+	rest := tag
+	for rest != "" {
+		var part string
+		if strings.HasPrefix(rest, "regex=") {
+			part, rest = rest, ""
+		} else {
+			part, rest, _ = strings.Cut(rest, ",")
+		}
+
+		name, param, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if name == "" {
+			continue
+		}
+
+		r := rule{name: name, param: param}
+		switch {
+		case name == "regex":
+			re, err := regexp.Compile(param)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regex %q: %w", param, err)
+			}
+			r.regex = re
+		case slices.Contains(builtin, name):
+		default:
+			fn, ok := custom.Load(name)
+			if !ok {
+				return nil, fmt.Errorf("unknown validation rule %q", name)
+			}
+			r.fn = fn.(Func)
+		}
+		rules = append(rules, r)
+	}
+
+	parsed.Store(tag, rules)
+	return rules, nil
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func isNumber(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func hasLength(k reflect.Kind) bool {
+	switch k {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return true
+	}
+	return false
+}
+
+// Check verifies at startup that tag parses and that each of its rules
+// applies to values of type t.
+func Check(tag string, t reflect.Type) error {
+	rules, err := parse(tag)
+	if err != nil {
+		return err
+	}
+
+	kind := indirect(t).Kind()
+	for _, r := range rules {
+		switch r.name {
+		case "min", "max", "len":
+			if !isNumber(kind) && !hasLength(kind) {
+				return fmt.Errorf("rule %q does not apply to %s", r.name, t)
+			}
+			if _, err := strconv.ParseFloat(r.param, 64); err != nil {
+				return fmt.Errorf("rule %q needs a numeric parameter, got %q", r.name, r.param)
+			}
+		case "email", "url", "uuid", "regex":
+			if kind != reflect.String {
+				return fmt.Errorf("rule %q applies only to strings, not %s", r.name, t)
+			}
+		case "oneof":
+			if kind != reflect.String && !isNumber(kind) {
+				return fmt.Errorf("rule %q applies only to strings and numbers, not %s", r.name, t)
+			}
+		}
+	}
+	return nil
+}
+
+// CheckType runs Check on every validate tag reachable from t through
+// struct fields, slice elements and pointers.
+func CheckType(t reflect.Type) error {
+	return checkType(t, map[reflect.Type]bool{})
+}
+
+func checkType(t reflect.Type, seen map[reflect.Type]bool) error {
+	t = indirect(t)
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return checkType(t.Elem(), seen)
+	case reflect.Struct:
+	default:
+		return nil
+	}
+
+	if seen[t] {
+		return nil
+	}
+	seen[t] = true
+
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		if tag := field.Tag.Get("validate"); tag != "" && tag != "-" {
+			if err := Check(tag, field.Type); err != nil {
+				return fmt.Errorf("field %s: %w", field.Name, err)
+			}
+		}
+		if err := checkType(field.Type, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Value applies tag to v and then validates any structs nested inside it.
+// Rules other than required are skipped for nil pointers.
+func Value(v reflect.Value, tag string, loc []string) []Failure {
+	rules, err := parse(tag)
+	if err != nil {
+		return []Failure{newFailure(loc, types.Invalid, err.Error())}
+	}
+
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			if slices.ContainsFunc(rules, func(r rule) bool { return r.name == "required" }) {
+				return []Failure{newFailure(loc, types.Missing, "field required")}
+			}
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	if v.IsZero() && slices.ContainsFunc(rules, func(r rule) bool { return r.name == "omitempty" }) {
+		return nil
+	}
+
+	var failures []Failure
+	for _, r := range rules {
+		if failure := r.apply(v, loc); failure != nil {
+			failures = append(failures, *failure)
+		}
+	}
+
+	return append(failures, nested(v, loc)...)
+}
+
+// Struct validates the fields of the struct v, naming them in loc by
+// their JSON names.
+func Struct(v reflect.Value, loc []string) []Failure {
+	var failures []Failure
+	for _, field := range reflect.VisibleFields(v.Type()) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+
+		tag := field.Tag.Get("validate")
+		if tag == "-" {
+			continue
+		}
+
+		name := JSONName(field)
+		if name == "" {
+			continue
+		}
+
+		// Fields promoted through a nil embedded pointer are validated as
+		// zero values.
+		value, err := v.FieldByIndexErr(field.Index)
+		if err != nil {
+			value = reflect.Zero(field.Type)
+		}
+		failures = append(failures, Value(value, tag, append(slices.Clip(loc), name))...)
+	}
+	return failures
+}
+
+func nested(v reflect.Value, loc []string) []Failure {
+	switch v.Kind() {
+	case reflect.Struct:
+		return Struct(v, loc)
+	case reflect.Slice, reflect.Array:
+		elem := indirect(v.Type().Elem())
+		if elem.Kind() != reflect.Struct && elem.Kind() != reflect.Slice {
+			return nil
+		}
+		var failures []Failure
+		for i := 0; i < v.Len(); i++ {
+			failures = append(failures, Value(v.Index(i), "", append(slices.Clip(loc), strconv.Itoa(i)))...)
+		}
+		return failures
+	}
+	return nil
+}
+
+// JSONName returns the key a field is encoded under, or "" when the json
+// tag hides it.
+func JSONName(field reflect.StructField) string {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return ""
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name
+	}
+	return field.Name
+}
+
+func (r rule) apply(v reflect.Value, loc []string) *Failure {
+	fail := func(typ types.IssueType, format string, args ...any) *Failure {
+		failure := newFailure(loc, typ, format, args...)
+		return &failure
+	}
+
+	switch r.name {
+	case "omitempty":
+		return nil
+	case "required":
+		if v.IsZero() {
+			return fail(types.Missing, "field required")
+		}
+	case "min", "max", "len":
+		limit, _ := strconv.ParseFloat(r.param, 64)
+		return r.bounds(v, limit, fail)
+	case "email":
+		addr, err := mail.ParseAddress(v.String())
+		if err != nil || addr.Address != v.String() {
+			return fail(types.InvalidFormat, "must be a valid email address")
+		}
+	case "url":
+		u, err := url.ParseRequestURI(v.String())
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fail(types.InvalidFormat, "must be a valid absolute URL")
+		}
+	case "uuid":
+		if _, err := uuid.Parse(v.String()); err != nil {
+			return fail(types.InvalidFormat, "must be a valid UUID")
+		}
+	case "oneof":
+		options := strings.Fields(r.param)
+		if !slices.Contains(options, fmt.Sprint(v.Interface())) {
+			return fail(types.NotAllowed, "must be one of: %s", strings.Join(options, ", "))
+		}
+	case "regex":
+		if !r.regex.MatchString(v.String()) {
+			return fail(types.PatternMismatch, "must match the pattern %s", r.regex)
+		}
+	default:
+		if err := r.fn(v.Interface(), r.param); err != nil {
+			return fail(types.CustomRule, "%s", err.Error())
+		}
+	}
+	return nil
+}
+
+func (r rule) bounds(v reflect.Value, limit float64, fail func(types.IssueType, string, ...any) *Failure) *Failure {
+	var n float64
+	isLength := hasLength(v.Kind())
+
+	switch {
+	case isLength && v.Kind() == reflect.String:
+		n = float64(utf8.RuneCountInString(v.String()))
+	case isLength:
+		n = float64(v.Len())
+	case v.CanInt():
+		n = float64(v.Int())
+	case v.CanUint():
+		n = float64(v.Uint())
+	case v.CanFloat():
+		n = v.Float()
+	default:
+		return nil
+	}
+
+	small, large := types.TooSmall, types.TooLarge
+	exactly, atLeast, atMost := "must be exactly %s", "must be at least %s", "must be at most %s"
+	if isLength {
+		small, large = types.TooShort, types.TooLong
+		exactly, atLeast, atMost = "length must be exactly %s", "length must be at least %s", "length must be at most %s"
+	}
+
+	switch {
+	case (r.name == "min" || r.name == "len") && n < limit:
+		if r.name == "len" {
+			return fail(small, exactly, r.param)
+		}
+		return fail(small, atLeast, r.param)
+	case (r.name == "max" || r.name == "len") && n > limit:
+		if r.name == "len" {
+			return fail(large, exactly, r.param)
+		}
+		return fail(large, atMost, r.param)
+	}
+	return nil
+}
@@ -71,7 +71,7 @@ func (e *WebsocketEmitter) Emit(event string, data interface{}) error {
 		if client := hub.clients[clientID]; client != nil && client != e.source {
 			if err := client.Replit(event, data); err != nil {
 				errs = append(errs, err)
-				e.source.logger.Error("Error en emisión",
+				e.source.logger.Error("Broadcast failed",
 					zap.String("room", string(e.room)),
 					zap.Error(err))
 			}
@@ -79,7 +79,7 @@ func (e *WebsocketEmitter) Emit(event string, data interface{}) error {
 	}
 
 	if len(errs) > 0 {
-		return fmt.Errorf("errores durante la emisión: %v", errs)
+		return fmt.Errorf("errors while broadcasting: %v", errs)
 	}
 	return nil
 }